@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/services"
+)
+
+// jackpotConfigFromEnv reads JACKPOT_CONTRIBUTION_RATE and JACKPOT_SEED_AMOUNT,
+// falling back to services.DefaultJackpotConfig for unset values.
+func jackpotConfigFromEnv() services.JackpotConfig {
+	config := services.DefaultJackpotConfig
+	config.ContributionRate = envFloat("JACKPOT_CONTRIBUTION_RATE", config.ContributionRate)
+	config.SeedAmount = envMoney("JACKPOT_SEED_AMOUNT", config.SeedAmount)
+	return config
+}
+
+// envFloat returns the float value of the environment variable name, or def
+// when it is not set.
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return f
+}
+
+// envMoney returns the amount in the environment variable name, or def when it
+// is not set.
+func envMoney(name string, def models.Money) models.Money {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	m, err := models.ParseMoney(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return m
+}
+
+// envDuration returns the duration in the environment variable name, such as
+// "30s" or "5m", or def when it is not set.
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s %q: must be a positive duration", name, value)
+	}
+	return d
+}
+
+// xpRulesFromEnv reads XP_PER_CHALLENGE, XP_PER_JACKPOT_WIN,
+// XP_PER_ROOM_MINUTE and XP_PER_CHECK_IN, falling back to
+// services.DefaultXPRules for unset values.
+func xpRulesFromEnv() services.XPRules {
+	rules := services.DefaultXPRules
+	rules.Challenge = int64(envInt("XP_PER_CHALLENGE", int(rules.Challenge)))
+	rules.JackpotWin = int64(envInt("XP_PER_JACKPOT_WIN", int(rules.JackpotWin)))
+	rules.RoomMinute = int64(envInt("XP_PER_ROOM_MINUTE", int(rules.RoomMinute)))
+	rules.CheckIn = int64(envInt("XP_PER_CHECK_IN", int(rules.CheckIn)))
+	return rules
+}
+
+// logIngestConfigFromEnv reads LOG_QUEUE_SIZE, LOG_WORKERS, LOG_BATCH_SIZE
+// and LOG_FLUSH_INTERVAL, falling back to services.DefaultLogIngestConfig for
+// unset values.
+func logIngestConfigFromEnv() services.LogIngestConfig {
+	config := services.DefaultLogIngestConfig
+	config.QueueSize = envInt("LOG_QUEUE_SIZE", config.QueueSize)
+	config.Workers = envInt("LOG_WORKERS", config.Workers)
+	config.BatchSize = envInt("LOG_BATCH_SIZE", config.BatchSize)
+	config.FlushInterval = envDuration("LOG_FLUSH_INTERVAL", config.FlushInterval)
+	return config
+}
+
+// envInt returns the integer value of the environment variable name, or def
+// when it is not set.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return n
+}
+
+// logRetentionFromEnv reads LOG_RETENTION, a comma separated list of
+// action=period pairs such as "default=365d,Login=30d,Enter Room=2160h".
+// Periods are whole days or Go durations; "default" applies to the actions
+// not listed. Logs are kept forever when it is not set.
+func logRetentionFromEnv() services.LogRetention {
+	retention := services.LogRetention{Actions: make(map[models.LogAction]time.Duration)}
+	value := os.Getenv("LOG_RETENTION")
+	if value == "" {
+		return retention
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, period, ok := strings.Cut(pair, "=")
+		name, period = strings.TrimSpace(name), strings.TrimSpace(period)
+		d, err := parseRetentionPeriod(period)
+		if !ok || err != nil {
+			log.Fatalf("Invalid LOG_RETENTION entry %q: want action=period, e.g. Login=30d", pair)
+		}
+		if name == "default" {
+			retention.Default = d
+			continue
+		}
+		action := models.LogAction(name)
+		if !action.Valid() {
+			log.Fatalf("Unknown action %q in LOG_RETENTION", name)
+		}
+		retention.Actions[action] = d
+	}
+	return retention
+}
+
+// parseRetentionPeriod parses a number of days such as "30d" or a duration
+// such as "720h".
+func parseRetentionPeriod(period string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(period, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative period %q", period)
+	}
+	return d, err
+}
+
+// paymentProvidersFromEnv builds the providers named in the comma separated
+// PAYMENT_METHODS. Without any, top-ups are turned off; the "fake" provider
+// accepts every charge, so it is only used when listed.
+func paymentProvidersFromEnv() []services.PaymentProvider {
+	methods := os.Getenv("PAYMENT_METHODS")
+	if methods == "" {
+		log.Println("PAYMENT_METHODS is not set, top-ups are disabled")
+		return nil
+	}
+
+	var providers []services.PaymentProvider
+	for _, method := range strings.Split(methods, ",") {
+		switch strings.TrimSpace(method) {
+		case "fake":
+			log.Println("Using the fake payment provider, which accepts every top-up")
+			providers = append(providers, services.NewFakePaymentProvider())
+		default:
+			log.Fatalf("Unknown payment method %q in PAYMENT_METHODS", method)
+		}
+	}
+	return providers
+}
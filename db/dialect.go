@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+)
+
+// Dialect is the SQL flavour spoken by a database connection.
+type Dialect string
+
+const (
+	MySQL   Dialect = "mysql"
+	SQLite  Dialect = "sqlite"
+	Unknown Dialect = ""
+)
+
+// DialectOf reports which SQL flavour db speaks, based on its driver.
+func DialectOf(db *sql.DB) Dialect {
+	switch db.Driver().(type) {
+	case *mysql.MySQLDriver:
+		return MySQL
+	case *sqlite.Driver:
+		return SQLite
+	default:
+		return Unknown
+	}
+}
+
+// dropIndexOn matches MySQL's "DROP INDEX name ON table".
+var dropIndexOn = regexp.MustCompile(`DROP INDEX (\w+) ON \w+`)
+
+// toSQLite rewrites the MySQL-only parts of a migration script. In SQLite only
+// an INTEGER PRIMARY KEY column aliases the rowid and hands out new IDs, and
+// index names are global rather than per table.
+func toSQLite(script string) string {
+	script = strings.ReplaceAll(script, "INT PRIMARY KEY AUTO_INCREMENT", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	return dropIndexOn.ReplaceAllString(script, "DROP INDEX $1")
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	conn, err := Open("sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer conn.Close()
+
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	// Apply every migration
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Error migrating up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("Expected %d migrations applied, got %d", len(migrator.migrations), len(applied))
+	}
+
+	// A second run has nothing left to do
+	applied, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("Error re-running migrations: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected no pending migrations, got %d", len(applied))
+	}
+
+	// Roll back the latest migration
+	reverted, err := migrator.Down(1)
+	if err != nil {
+		t.Fatalf("Error migrating down: %v", err)
+	}
+	latest := migrator.migrations[len(migrator.migrations)-1]
+	if len(reverted) != 1 || reverted[0].Version != latest.Version {
+		t.Fatalf("Expected migration %d reverted, got %+v", latest.Version, reverted)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Error fetching status: %v", err)
+	}
+	for _, status := range statuses {
+		if want := status.Version != latest.Version; status.Applied != want {
+			t.Errorf("Expected migration %d applied=%v, got %v", status.Version, want, status.Applied)
+		}
+	}
+
+	// Roll everything back, then forward again
+	if _, err := migrator.Down(len(migrator.migrations)); err != nil {
+		t.Fatalf("Error rolling back all migrations: %v", err)
+	}
+	if _, err := migrator.Down(1); !errors.Is(err, ErrNoMigrationToRollBack) {
+		t.Errorf("Expected ErrNoMigrationToRollBack, got %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Error migrating up again: %v", err)
+	}
+}
+
+func TestMigrator_MigratesLegacyData(t *testing.T) {
+	conn, err := Open("sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer conn.Close()
+
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	// Start from the original create_tables.sql schema with existing data
+	if err := execScript(conn, toSQLite(migrator.migrations[0].Up)); err != nil {
+		t.Fatalf("Error creating legacy schema: %v", err)
+	}
+	if _, err := conn.Exec(`INSERT INTO levels (name) VALUES ('Beginner'), ('Advanced')`); err != nil {
+		t.Fatalf("Error inserting levels: %v", err)
+	}
+	if _, err := conn.Exec(`INSERT INTO players (name, level, balance) VALUES ('Alice', 'Advanced', 10)`); err != nil {
+		t.Fatalf("Error inserting player: %v", err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Error migrating up: %v", err)
+	}
+
+	var levelID int
+	if err := conn.QueryRow(`SELECT level_id FROM players WHERE name = 'Alice'`).Scan(&levelID); err != nil {
+		t.Fatalf("Error reading level_id: %v", err)
+	}
+	if levelID != 2 {
+		t.Errorf("Expected level_id 2, got %d", levelID)
+	}
+
+	// The existing balance opens the player's ledger account
+	var ledgerBalance, total float64
+	if err := conn.QueryRow(`SELECT amount FROM ledger_entries WHERE account = 'player:1'`).Scan(&ledgerBalance); err != nil {
+		t.Fatalf("Error reading opening balance: %v", err)
+	}
+	if ledgerBalance != 10 {
+		t.Errorf("Expected opening balance 10, got %.2f", ledgerBalance)
+	}
+	if err := conn.QueryRow(`SELECT SUM(amount) FROM ledger_entries`).Scan(&total); err != nil {
+		t.Fatalf("Error summing ledger: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("Expected a balanced ledger, got a total of %.2f", total)
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS players;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS logs;
+DROP TABLE IF EXISTS levels;
+DROP TABLE IF EXISTS challenge_results;
+DROP TABLE IF EXISTS challenges;
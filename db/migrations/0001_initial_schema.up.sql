@@ -1,5 +1,8 @@
+-- Initial schema, formerly create_tables.sql. IF NOT EXISTS lets databases
+-- created from that script adopt the migration history.
+
 -- Table: challenges
-CREATE TABLE challenges (
+CREATE TABLE IF NOT EXISTS challenges (
     id INT PRIMARY KEY AUTO_INCREMENT,
     player_id INT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -7,7 +10,7 @@ CREATE TABLE challenges (
 );
 
 -- Table: challenge_results
-CREATE TABLE challenge_results (
+CREATE TABLE IF NOT EXISTS challenge_results (
     id INT PRIMARY KEY AUTO_INCREMENT,
     player_id INT NOT NULL,
     won_jackpot BOOLEAN NOT NULL,
@@ -15,13 +18,13 @@ CREATE TABLE challenge_results (
 );
 
 -- Table: levels
-CREATE TABLE levels (
+CREATE TABLE IF NOT EXISTS levels (
     id INT PRIMARY KEY AUTO_INCREMENT,
     name VARCHAR(255) NOT NULL
 );
 
 -- Table: logs
-CREATE TABLE logs (
+CREATE TABLE IF NOT EXISTS logs (
     id INT PRIMARY KEY AUTO_INCREMENT,
     player_id INT NOT NULL,
     action VARCHAR(255) NOT NULL,
@@ -31,7 +34,7 @@ CREATE TABLE logs (
 );
 
 -- Table: payments
-CREATE TABLE payments (
+CREATE TABLE IF NOT EXISTS payments (
     id INT PRIMARY KEY AUTO_INCREMENT,
     method VARCHAR(255) NOT NULL,
     amount DECIMAL(10, 2) NOT NULL,
@@ -40,7 +43,7 @@ CREATE TABLE payments (
 );
 
 -- Table: players
-CREATE TABLE players (
+CREATE TABLE IF NOT EXISTS players (
     id INT PRIMARY KEY AUTO_INCREMENT,
     name VARCHAR(255) NOT NULL,
     level VARCHAR(255) NOT NULL,
@@ -48,7 +51,7 @@ CREATE TABLE players (
 );
 
 -- Table: reservations
-CREATE TABLE reservations (
+CREATE TABLE IF NOT EXISTS reservations (
     id INT PRIMARY KEY AUTO_INCREMENT,
     room_id INT NOT NULL,
     date DATE NOT NULL,
@@ -58,7 +61,7 @@ CREATE TABLE reservations (
 );
 
 -- Table: rooms
-CREATE TABLE rooms (
+CREATE TABLE IF NOT EXISTS rooms (
     id INT PRIMARY KEY AUTO_INCREMENT,
     name VARCHAR(255) NOT NULL,
     description TEXT,
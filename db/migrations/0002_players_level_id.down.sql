@@ -0,0 +1,5 @@
+ALTER TABLE players ADD COLUMN level VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE players SET level = COALESCE((SELECT name FROM levels WHERE levels.id = players.level_id), '');
+
+ALTER TABLE players DROP COLUMN level_id;
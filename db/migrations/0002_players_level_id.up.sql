@@ -0,0 +1,6 @@
+-- players.level held a level name, but a player references a row in levels.
+ALTER TABLE players ADD COLUMN level_id INT NULL;
+
+UPDATE players SET level_id = (SELECT id FROM levels WHERE levels.name = players.level);
+
+ALTER TABLE players DROP COLUMN level;
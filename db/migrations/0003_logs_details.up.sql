@@ -0,0 +1 @@
+ALTER TABLE logs ADD COLUMN details TEXT;
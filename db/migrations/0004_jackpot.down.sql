@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS jackpot_payouts;
+DROP TABLE IF EXISTS jackpot_pools;
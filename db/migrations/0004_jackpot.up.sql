@@ -0,0 +1,15 @@
+-- Table: jackpot_pools
+CREATE TABLE jackpot_pools (
+    id INT PRIMARY KEY,
+    balance DECIMAL(10, 2) NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+-- Table: jackpot_payouts
+CREATE TABLE jackpot_payouts (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    player_id INT NOT NULL,
+    challenge_id INT NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    created_at BIGINT NOT NULL
+);
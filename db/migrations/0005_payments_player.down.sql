@@ -0,0 +1,5 @@
+DROP INDEX idx_payments_player_id ON payments;
+
+ALTER TABLE payments DROP COLUMN type;
+
+ALTER TABLE payments DROP COLUMN player_id;
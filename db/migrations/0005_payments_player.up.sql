@@ -0,0 +1,6 @@
+-- Payments record every change to a player's balance.
+ALTER TABLE payments ADD COLUMN player_id INT NOT NULL DEFAULT 0;
+
+ALTER TABLE payments ADD COLUMN type VARCHAR(32) NOT NULL DEFAULT 'top_up';
+
+CREATE INDEX idx_payments_player_id ON payments (player_id);
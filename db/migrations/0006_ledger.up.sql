@@ -0,0 +1,32 @@
+-- Table: ledger_transactions
+CREATE TABLE ledger_transactions (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    kind VARCHAR(32) NOT NULL,
+    memo VARCHAR(255) NOT NULL,
+    reference VARCHAR(255) NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+-- Table: ledger_entries
+CREATE TABLE ledger_entries (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    transaction_id INT NOT NULL,
+    account VARCHAR(64) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL
+);
+
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries (account);
+
+-- Open the ledger with every existing player balance, funded by the house.
+INSERT INTO ledger_transactions (kind, memo, reference, created_at)
+SELECT 'adjustment', 'opening balance', CONCAT('player:', id), 0 FROM players WHERE balance <> 0;
+
+INSERT INTO ledger_entries (transaction_id, account, amount)
+SELECT t.id, t.reference, p.balance
+FROM ledger_transactions t JOIN players p ON t.reference = CONCAT('player:', p.id);
+
+INSERT INTO ledger_entries (transaction_id, account, amount)
+SELECT t.id, 'house:adjustments', -p.balance
+FROM ledger_transactions t JOIN players p ON t.reference = CONCAT('player:', p.id);
@@ -0,0 +1,5 @@
+DROP INDEX idx_reservations_room_date ON reservations;
+
+ALTER TABLE rooms DROP COLUMN slot_minutes;
+
+ALTER TABLE rooms DROP COLUMN capacity;
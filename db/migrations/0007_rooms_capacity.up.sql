@@ -0,0 +1,6 @@
+-- Rooms hold reservations of a fixed length for up to capacity players.
+ALTER TABLE rooms ADD COLUMN capacity INT NOT NULL DEFAULT 1;
+
+ALTER TABLE rooms ADD COLUMN slot_minutes INT NOT NULL DEFAULT 60;
+
+CREATE INDEX idx_reservations_room_date ON reservations (room_id, date);
@@ -0,0 +1,3 @@
+DROP INDEX idx_reservations_status ON reservations;
+
+ALTER TABLE reservations DROP COLUMN status;
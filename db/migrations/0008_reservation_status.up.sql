@@ -0,0 +1,4 @@
+-- Reservations made before statuses existed were already confirmed.
+ALTER TABLE reservations ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'confirmed';
+
+CREATE INDEX idx_reservations_status ON reservations (status);
@@ -0,0 +1,3 @@
+ALTER TABLE rooms DROP COLUMN closes_at;
+
+ALTER TABLE rooms DROP COLUMN opens_at;
@@ -0,0 +1,4 @@
+-- Daily operating hours in UTC, as HH:MM.
+ALTER TABLE rooms ADD COLUMN opens_at VARCHAR(5) NOT NULL DEFAULT '09:00';
+
+ALTER TABLE rooms ADD COLUMN closes_at VARCHAR(5) NOT NULL DEFAULT '22:00';
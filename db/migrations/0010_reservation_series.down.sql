@@ -0,0 +1,5 @@
+DROP INDEX idx_reservations_series_id ON reservations;
+
+ALTER TABLE reservations DROP COLUMN series_id;
+
+DROP TABLE reservation_series;
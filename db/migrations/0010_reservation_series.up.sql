@@ -0,0 +1,14 @@
+-- Recurring reservations expand into one reservations row per occurrence.
+CREATE TABLE reservation_series (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    room_id INT NOT NULL,
+    player_id INT NOT NULL,
+    time VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    rule VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE reservations ADD COLUMN series_id INT NULL;
+
+CREATE INDEX idx_reservations_series_id ON reservations (series_id);
@@ -0,0 +1,3 @@
+DROP INDEX idx_room_occupants_room_id ON room_occupants;
+
+DROP TABLE room_occupants;
@@ -0,0 +1,14 @@
+-- Room statuses were free-form; keep the known ones and make the rest available.
+UPDATE rooms SET status = LOWER(status);
+
+UPDATE rooms SET status = 'available'
+WHERE status IS NULL OR status NOT IN ('available', 'maintenance', 'closed');
+
+-- Players currently inside a room. A player is in at most one room at a time.
+CREATE TABLE room_occupants (
+    player_id INT PRIMARY KEY,
+    room_id INT NOT NULL,
+    entered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_room_occupants_room_id ON room_occupants (room_id);
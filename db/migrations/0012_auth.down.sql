@@ -0,0 +1,9 @@
+DROP INDEX idx_sessions_expires_at ON sessions;
+
+DROP INDEX idx_sessions_player_id ON sessions;
+
+DROP TABLE sessions;
+
+DROP INDEX idx_player_credentials_username ON player_credentials;
+
+DROP TABLE player_credentials;
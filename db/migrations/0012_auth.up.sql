@@ -0,0 +1,21 @@
+-- Login credentials, one set per player.
+CREATE TABLE player_credentials (
+    player_id INT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_player_credentials_username ON player_credentials (username);
+
+-- Sessions are looked up by the SHA-256 hash of their token.
+CREATE TABLE sessions (
+    token_hash CHAR(64) PRIMARY KEY,
+    player_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_player_id ON sessions (player_id);
+
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
@@ -0,0 +1,2 @@
+-- Every player is a plain player until an admin gives them another role.
+ALTER TABLE players ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'player';
@@ -0,0 +1,3 @@
+DROP INDEX idx_logs_action ON logs;
+DROP INDEX idx_logs_player_id ON logs;
+DROP INDEX idx_logs_timestamp ON logs;
@@ -0,0 +1,5 @@
+-- Log queries filter on a player, an action or a time range and page through
+-- the results in (timestamp, id) order.
+CREATE INDEX idx_logs_timestamp ON logs (timestamp, id);
+CREATE INDEX idx_logs_player_id ON logs (player_id, timestamp, id);
+CREATE INDEX idx_logs_action ON logs (action, timestamp, id);
@@ -0,0 +1 @@
+DROP TABLE log_details;
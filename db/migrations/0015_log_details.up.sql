@@ -0,0 +1,15 @@
+-- Log details are a JSON object in logs.details. Each field is also kept here
+-- so logs can be searched by their details.
+CREATE TABLE log_details (
+    log_id INT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    PRIMARY KEY (log_id, name)
+);
+
+CREATE INDEX idx_log_details_name_value ON log_details (name, value);
+
+-- Older logs have free-text details, which are read back as the note.
+INSERT INTO log_details (log_id, name, value)
+SELECT id, 'note', SUBSTR(details, 1, 255) FROM logs
+WHERE details IS NOT NULL AND details <> '';
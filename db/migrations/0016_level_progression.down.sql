@@ -0,0 +1,3 @@
+ALTER TABLE players DROP COLUMN xp;
+ALTER TABLE levels DROP COLUMN min_xp;
+ALTER TABLE levels DROP COLUMN sort_order;
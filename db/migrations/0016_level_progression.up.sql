@@ -0,0 +1,13 @@
+-- Levels form a ladder: sort_order ranks them and min_xp is the experience a
+-- player needs to reach each one.
+ALTER TABLE levels ADD COLUMN sort_order INT NOT NULL DEFAULT 0;
+ALTER TABLE levels ADD COLUMN min_xp BIGINT NOT NULL DEFAULT 0;
+
+-- Existing levels keep the order they were created in, 1000 XP apart.
+UPDATE levels SET sort_order = id, min_xp = (id - 1) * 1000;
+
+ALTER TABLE players ADD COLUMN xp BIGINT NOT NULL DEFAULT 0;
+
+-- Players start with the experience their current level needs, so that the
+-- first award does not move them back down the ladder.
+UPDATE players SET xp = COALESCE((SELECT min_xp FROM levels WHERE levels.id = players.level_id), 0);
@@ -0,0 +1,4 @@
+DROP TABLE level_rooms;
+ALTER TABLE levels DROP COLUMN challenge_cooldown_seconds;
+ALTER TABLE levels DROP COLUMN challenge_fee;
+ALTER TABLE levels DROP COLUMN max_weekly_reservations;
@@ -0,0 +1,12 @@
+-- Per-level gameplay rules. Zero and NULL values keep the standard rules.
+ALTER TABLE levels ADD COLUMN max_weekly_reservations INT NOT NULL DEFAULT 0;
+ALTER TABLE levels ADD COLUMN challenge_fee DECIMAL(10, 2) NULL;
+ALTER TABLE levels ADD COLUMN challenge_cooldown_seconds INT NOT NULL DEFAULT 0;
+
+-- The rooms each level may reserve. A level without rows may reserve every
+-- room.
+CREATE TABLE level_rooms (
+    level_id INT NOT NULL,
+    room_id INT NOT NULL,
+    PRIMARY KEY (level_id, room_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE challenges DROP COLUMN definition_id;
+DROP TABLE challenge_definitions;
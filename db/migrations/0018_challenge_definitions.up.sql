@@ -0,0 +1,20 @@
+-- Challenges players can join. The first row is the challenge that used to
+-- be hard-coded.
+CREATE TABLE challenge_definitions (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    fee DECIMAL(10, 2) NOT NULL,
+    win_probability DOUBLE NOT NULL,
+    duration_seconds INT NOT NULL DEFAULT 0,
+    cooldown_seconds INT NOT NULL DEFAULT 0,
+    max_per_day INT NOT NULL DEFAULT 0,
+    active_from TIMESTAMP NULL,
+    active_until TIMESTAMP NULL
+);
+
+INSERT INTO challenge_definitions (name, fee, win_probability, duration_seconds, cooldown_seconds)
+VALUES ('Endless Challenge', 20.01, 0.01, 30, 30);
+
+-- Every attempt so far was at that challenge.
+ALTER TABLE challenges ADD COLUMN definition_id INT NOT NULL DEFAULT 0;
+UPDATE challenges SET definition_id = 1;
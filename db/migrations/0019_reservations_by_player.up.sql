@@ -0,0 +1,2 @@
+-- Weekly reservation limits look up a player's reservations by date.
+CREATE INDEX idx_reservations_player_date ON reservations (player_id, date);
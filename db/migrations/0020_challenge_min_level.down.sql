@@ -0,0 +1 @@
+ALTER TABLE challenge_definitions DROP COLUMN min_level_order;
@@ -0,0 +1,3 @@
+-- The lowest level order a player needs to join a challenge; 0 lets every
+-- player join.
+ALTER TABLE challenge_definitions ADD COLUMN min_level_order INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- The experience players had before the backfill is not kept, so there is
+-- nothing to undo.
@@ -0,0 +1,4 @@
+-- Databases that ran 0016 before it backfilled experience: raise every
+-- player to the threshold of the level they are at.
+UPDATE players SET xp = (SELECT min_xp FROM levels WHERE levels.id = players.level_id)
+WHERE xp < (SELECT min_xp FROM levels WHERE levels.id = players.level_id);
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/middleware"
+)
+
+// actingPlayer returns the player a request acts for: playerID, or the
+// authenticated player when playerID is 0. Players may only act for
+// themselves; operators and admins may act for anyone. If the request may
+// not, it responds 401 or 403 and returns false.
+func actingPlayer(c *gin.Context, playerID int) (int, bool) {
+	player, ok := middleware.CurrentPlayer(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return 0, false
+	}
+	if playerID == 0 {
+		return player.ID, true
+	}
+	if !middleware.CanActFor(c, playerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "players can only act for themselves"})
+		return 0, false
+	}
+	return playerID, true
+}
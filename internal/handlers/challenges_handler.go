@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"oxo_game/internal/middleware"
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
 	"oxo_game/internal/services"
 )
 
@@ -18,24 +23,68 @@ func NewChallengeHandler(challengeService services.ChallengeService) *ChallengeH
 	}
 }
 
+// participateChallengeRequest is the optional body of a challenge entry.
+// PlayerID, when given, must be the authenticated player.
+type participateChallengeRequest struct {
+	PlayerID int `json:"player_id"`
+}
+
+// ParticipateChallenge enters the authenticated player into the first open
+// challenge.
 func (h *ChallengeHandler) ParticipateChallenge(c *gin.Context) {
-	playerID, err := strconv.Atoi(c.Param("player_id"))
+	h.enter(c, h.challengeService.ParticipateChallenge)
+}
+
+// JoinChallenge enters the authenticated player into the challenge with the
+// ID in the path.
+func (h *ChallengeHandler) JoinChallenge(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge ID"})
+		return
+	}
+	h.enter(c, func(playerID int) (*services.ChallengeOutcome, error) {
+		return h.challengeService.JoinChallenge(playerID, id)
+	})
+}
+
+// enter enters the authenticated player into a challenge with join.
+func (h *ChallengeHandler) enter(c *gin.Context, join func(playerID int) (*services.ChallengeOutcome, error)) {
+	player, ok := middleware.CurrentPlayer(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+	var req participateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
 		return
 	}
+	if req.PlayerID != 0 && req.PlayerID != player.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "players can only enter challenges themselves"})
+		return
+	}
 
-	wonJackpot, err := h.challengeService.ParticipateChallenge(playerID)
+	outcome, err := join(player.ID)
 	if err != nil {
-		if err.Error() == "player is on cooldown" {
+		switch {
+		case errors.Is(err, services.ErrPlayerOnCooldown), errors.Is(err, services.ErrChallengeLimitReached):
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
-		} else {
+		case errors.Is(err, services.ErrChallengeInactive):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLevelTooLow):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrPlayerNotFound), errors.Is(err, services.ErrChallengeDefinitionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"won_jackpot": wonJackpot})
+	c.JSON(http.StatusOK, outcome)
 }
 
 func (h *ChallengeHandler) ListLatestChallenges(c *gin.Context) {
@@ -47,3 +96,78 @@ func (h *ChallengeHandler) ListLatestChallenges(c *gin.Context) {
 	challenges := h.challengeService.ListLatestChallenges(n)
 	c.JSON(http.StatusOK, challenges)
 }
+
+func (h *ChallengeHandler) ListDefinitions(c *gin.Context) {
+	c.JSON(http.StatusOK, h.challengeService.ListDefinitions())
+}
+
+func (h *ChallengeHandler) GetDefinition(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge ID"})
+		return
+	}
+	definition, err := h.challengeService.GetDefinition(id)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, definition)
+}
+
+func (h *ChallengeHandler) CreateDefinition(c *gin.Context) {
+	var definition models.ChallengeDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+	created, err := h.challengeService.CreateDefinition(definition)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *ChallengeHandler) UpdateDefinition(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge ID"})
+		return
+	}
+	var definition models.ChallengeDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+	updated, err := h.challengeService.UpdateDefinition(id, definition)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *ChallengeHandler) DeleteDefinition(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge ID"})
+		return
+	}
+	if err := h.challengeService.DeleteDefinition(id); err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "challenge deleted successfully"})
+}
+
+func respondDefinitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChallengeDefinition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChallengeDefinitionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/models"
+	"oxo_game/internal/services"
+)
+
+// eventsHeartbeat is how often an idle stream sends a comment, so proxies
+// do not close it.
+const eventsHeartbeat = 15 * time.Second
+
+type EventsHandler struct {
+	bus *services.EventBus
+}
+
+func NewEventsHandler(bus *services.EventBus) *EventsHandler {
+	return &EventsHandler{
+		bus: bus,
+	}
+}
+
+// streamedEvent is the data of one Server-Sent Event.
+type streamedEvent struct {
+	ID       uint64            `json:"id"`
+	Topics   []string          `json:"topics"`
+	Action   models.LogAction  `json:"action"`
+	PlayerID int               `json:"player_id,omitempty"`
+	Details  models.LogDetails `json:"details"`
+	Time     time.Time         `json:"time"`
+}
+
+// StreamEvents streams events as Server-Sent Events. The topic parameter is
+// a comma separated list of topics and player_id limits the stream to one
+// player's events. A client that reconnects with the Last-Event-ID header,
+// or the last_event_id parameter, first gets the events it missed.
+func (h *EventsHandler) StreamEvents(c *gin.Context) {
+	var filter services.EventFilter
+	if v := c.Query("topic"); v != "" {
+		for _, topic := range strings.Split(v, ",") {
+			if !validTopic(topic) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown topic %q, want one of %s", topic, strings.Join(services.Topics, ", "))})
+				return
+			}
+			filter.Topics = append(filter.Topics, topic)
+		}
+	}
+	if v := c.Query("player_id"); v != "" {
+		var err error
+		if filter.PlayerID, err = strconv.Atoi(v); err != nil || filter.PlayerID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+			return
+		}
+	}
+	var lastID uint64
+	if v := c.GetHeader("Last-Event-ID"); v != "" || c.Query("last_event_id") != "" {
+		if v == "" {
+			v = c.Query("last_event_id")
+		}
+		var err error
+		if lastID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid last event ID"})
+			return
+		}
+	}
+
+	sub := h.bus.Subscribe(filter, lastID)
+	defer h.bus.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case e, ok := <-sub.Events:
+			if !ok {
+				// Dropped for falling behind, or shutting down; the client
+				// reconnects with the last ID it got
+				return
+			}
+			data, err := json.Marshal(streamedEvent{
+				ID:       e.ID,
+				Topics:   e.Topics,
+				Action:   e.Action,
+				PlayerID: e.PlayerID,
+				Details:  e.Details,
+				Time:     e.Time.UTC(),
+			})
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Topics[0], data)
+		}
+		c.Writer.Flush()
+	}
+}
+
+func validTopic(topic string) bool {
+	for _, t := range services.Topics {
+		if topic == t {
+			return true
+		}
+	}
+	return false
+}
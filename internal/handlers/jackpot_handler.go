@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/services"
+)
+
+type JackpotHandler struct {
+	jackpotService services.JackpotService
+}
+
+func NewJackpotHandler(jackpotService services.JackpotService) *JackpotHandler {
+	return &JackpotHandler{
+		jackpotService: jackpotService,
+	}
+}
+
+func (h *JackpotHandler) GetPool(c *gin.Context) {
+	pool, err := h.jackpotService.GetPool()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pool)
+}
+
+func (h *JackpotHandler) ListPayouts(c *gin.Context) {
+	n, err := strconv.Atoi(c.Query("n"))
+	if err != nil || n <= 0 {
+		n = 10 // Default to 10 if n is invalid or not provided
+	}
+
+	payouts := h.jackpotService.ListPayouts(n)
+	c.JSON(http.StatusOK, payouts)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+	"oxo_game/internal/services"
+)
+
+type LedgerHandler struct {
+	ledgerService services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+type adjustmentRequest struct {
+	Kind   string       `json:"kind"`
+	Amount models.Money `json:"amount"`
+	Memo   string       `json:"memo"`
+}
+
+func (h *LedgerHandler) ListPlayerTransactions(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+
+	transactions, err := h.ledgerService.ListPlayerTransactions(playerID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPlayerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, transactions)
+}
+
+func (h *LedgerHandler) AdjustBalance(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+	var req adjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	transaction, err := h.ledgerService.Adjust(playerID, req.Kind, req.Amount, req.Memo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAdjustment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrPlayerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, transaction)
+}
+
+func (h *LedgerHandler) Reconcile(c *gin.Context) {
+	mismatches, err := h.ledgerService.Reconcile()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, mismatches)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -35,7 +36,7 @@ func (h *LevelsHandler) GetLevelByID(c *gin.Context) {
 	}
 	level, err := h.service.GetLevelByID(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondLevelError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, level)
@@ -47,10 +48,67 @@ func (h *LevelsHandler) CreateLevel(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
-	id, err := h.service.CreateLevel(level.Name)
+	id, err := h.service.CreateLevel(level)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondLevelError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
+
+func (h *LevelsHandler) UpdateLevel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid level ID"})
+		return
+	}
+	var level models.Level
+	if err := c.ShouldBindJSON(&level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+	updated, err := h.service.UpdateLevel(id, level)
+	if err != nil {
+		respondLevelError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteLevel deletes a level. Players still at the level are moved to the
+// level given by the fallback_level_id parameter; without one, the delete is
+// refused while the level is in use.
+func (h *LevelsHandler) DeleteLevel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid level ID"})
+		return
+	}
+	var fallbackID int
+	if v := c.Query("fallback_level_id"); v != "" {
+		if fallbackID, err = strconv.Atoi(v); err != nil || fallbackID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fallback level ID"})
+			return
+		}
+	}
+	if err := h.service.DeleteLevel(id, fallbackID); err != nil {
+		respondLevelError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "level deleted successfully"})
+}
+
+func respondLevelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLevel), errors.Is(err, services.ErrLevelOutOfOrder),
+		errors.Is(err, services.ErrUnknownLevel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLevelNameTaken), errors.Is(err, services.ErrLevelOrderTaken),
+		errors.Is(err, services.ErrLevelInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLevelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
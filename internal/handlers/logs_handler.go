@@ -1,37 +1,209 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
 	"oxo_game/internal/services"
 )
 
-var (
-	ErrLogNotFound = errors.New("log not found")
-)
-
 type LogHandler struct {
-	service services.LogService
+	service  services.LogService
+	ingester *services.LogIngester
 }
 
-func NewLogsHandler(service services.LogService) *LogHandler {
+func NewLogsHandler(service services.LogService, ingester *services.LogIngester) *LogHandler {
 	return &LogHandler{
-		service: service,
+		service:  service,
+		ingester: ingester,
 	}
 }
 
-func (h *LogHandler) GetAllLogs(c *gin.Context) {
-	logs, err := h.service.GetAllLogs()
+// QueryLogs lists the logs matching the player_id, action, start_time and
+// end_time query parameters and any details.<name> parameters, oldest first
+// or newest first with order=desc.
+// A page holds at most limit logs; when there are more, the X-Next-Cursor
+// header holds the cursor parameter for the next page.
+func (h *LogHandler) QueryLogs(c *gin.Context) {
+	q, ok := parseLogQuery(c)
+	if !ok {
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+			return
+		}
+	}
+
+	page, err := h.service.QueryLogs(q, c.Query("cursor"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondLogQueryError(c, err)
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Logs)
+}
+
+// logExportColumns is the header row of a CSV export.
+var logExportColumns = []string{"id", "player_id", "action", "time", "timestamp", "created_at", "updated_at", "details"}
+
+// ExportLogs streams every log matching the same filters as QueryLogs as a
+// download, in format=csv or format=ndjson (the default). The logs are
+// written as they are read, so exports of any size use little memory. If
+// reading fails part way, the response is cut short.
+func (h *LogHandler) ExportLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+	q, ok := parseLogQuery(c)
+	if !ok {
+		return
+	}
+
+	var (
+		started bool
+		csvw    *csv.Writer
+		enc     *json.Encoder
+	)
+	start := func() {
+		started = true
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="logs.%s"`, format))
+		if format == "csv" {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			csvw = csv.NewWriter(c.Writer)
+			csvw.Write(logExportColumns)
+		} else {
+			c.Header("Content-Type", "application/x-ndjson")
+			enc = json.NewEncoder(c.Writer)
+		}
+		c.Status(http.StatusOK)
+	}
+
+	written := 0
+	err := h.service.ExportLogs(q, func(entry models.Log) error {
+		if !started {
+			start()
+		}
+		if enc != nil {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		} else {
+			details, err := json.Marshal(entry.Details)
+			if err != nil {
+				return err
+			}
+			csvw.Write([]string{
+				strconv.Itoa(entry.ID),
+				strconv.Itoa(entry.PlayerID),
+				string(entry.Action),
+				time.Unix(entry.Timestamp, 0).UTC().Format(time.RFC3339),
+				strconv.FormatInt(entry.Timestamp, 10),
+				strconv.FormatInt(entry.CreatedAt, 10),
+				strconv.FormatInt(entry.UpdatedAt, 10),
+				string(details),
+			})
+			csvw.Flush()
+			if err := csvw.Error(); err != nil {
+				return err
+			}
+		}
+		if written++; written%100 == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			respondLogQueryError(c, err)
+			return
+		}
+		log.Printf("Error exporting logs after %d rows: %v", written, err)
+		c.Abort()
 		return
 	}
-	c.JSON(http.StatusOK, logs)
+	if !started {
+		start()
+	}
+	if csvw != nil {
+		csvw.Flush()
+	}
+}
+
+// parseLogQuery reads the filters shared by QueryLogs and ExportLogs. It
+// responds with 400 and returns false when one is malformed.
+func parseLogQuery(c *gin.Context) (repositories.LogQuery, bool) {
+	var (
+		q   repositories.LogQuery
+		err error
+	)
+	if v := c.Query("player_id"); v != "" {
+		if q.PlayerID, err = strconv.Atoi(v); err != nil || q.PlayerID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+			return q, false
+		}
+	}
+	q.Action = models.LogAction(c.Query("action"))
+	if v := c.Query("start_time"); v != "" {
+		if q.StartTime, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_time parameter"})
+			return q, false
+		}
+	}
+	if v := c.Query("end_time"); v != "" {
+		if q.EndTime, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time parameter"})
+			return q, false
+		}
+	}
+	for param, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(param, "details."); ok {
+			if q.Details == nil {
+				q.Details = make(map[string]string)
+			}
+			q.Details[name] = values[0]
+		}
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return q, false
+	}
+	return q, true
+}
+
+func respondLogQueryError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidLogAction) ||
+		errors.Is(err, services.ErrInvalidLogQuery) ||
+		errors.Is(err, services.ErrInvalidLogCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetLogSchemas lists the details each action takes.
+func (h *LogHandler) GetLogSchemas(c *gin.Context) {
+	c.JSON(http.StatusOK, models.LogDetailSchemas)
 }
 
 func (h *LogHandler) GetLogByID(c *gin.Context) {
@@ -43,7 +215,7 @@ func (h *LogHandler) GetLogByID(c *gin.Context) {
 
 	log, err := h.service.GetLogByID(id)
 	if err != nil {
-		if err == ErrLogNotFound {
+		if errors.Is(err, repositories.ErrLogNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "log not found"})
 			return
 		}
@@ -59,6 +231,10 @@ func (h *LogHandler) CreateLog(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
+	var ok bool
+	if log.PlayerID, ok = actingPlayer(c, log.PlayerID); !ok {
+		return
+	}
 
 	// Set timestamps
 	now := time.Now().Unix()
@@ -68,6 +244,10 @@ func (h *LogHandler) CreateLog(c *gin.Context) {
 
 	id, err := h.service.CreateLog(log)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidLogAction) || errors.Is(err, models.ErrInvalidLogDetails) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,58 +255,45 @@ func (h *LogHandler) CreateLog(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
-func (h *LogHandler) GetLogsByPlayerID(c *gin.Context) {
-	playerID, err := strconv.Atoi(c.Query("player_id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
-		return
-	}
-
-	logs, err := h.service.GetLogsByPlayerID(playerID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// CreateLogBatch queues a JSON array of log entries to be written in the
+// background and responds 202 once they are queued. Each entry is checked
+// like CreateLog; if any is invalid, none are queued. When the queue is full
+// it responds 503 and the sender should retry later.
+func (h *LogHandler) CreateLogBatch(c *gin.Context) {
+	var entries []models.Log
+	if err := c.BindJSON(&entries); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
-
-	c.JSON(http.StatusOK, logs)
-}
-
-func (h *LogHandler) GetLogsByAction(c *gin.Context) {
-	action := c.Query("action")
-	if action == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "action parameter is required"})
-		return
+	for i := range entries {
+		var ok bool
+		if entries[i].PlayerID, ok = actingPlayer(c, entries[i].PlayerID); !ok {
+			return
+		}
 	}
 
-	logs, err := h.service.GetLogsByAction(action)
+	accepted, err := h.ingester.Enqueue(entries)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrLogQueueFull), errors.Is(err, services.ErrLogIngesterClosed):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLogBatchTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidLogAction),
+			errors.Is(err, models.ErrInvalidLogDetails):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
-
-	c.JSON(http.StatusOK, logs)
+	c.JSON(http.StatusAccepted, gin.H{"accepted": accepted})
 }
 
-func (h *LogHandler) GetLogsByTimeRange(c *gin.Context) {
-	startTime, err := strconv.ParseInt(c.Query("start_time"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_time parameter"})
-		return
-	}
-
-	endTime, err := strconv.ParseInt(c.Query("end_time"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time parameter"})
-		return
-	}
-
-	logs, err := h.service.GetLogsByTimeRange(startTime, endTime)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, logs)
+// GetIngestStats reports the batch queue's depth and counters.
+func (h *LogHandler) GetIngestStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.ingester.Stats())
 }
 
 func (h *LogHandler) DeleteLog(c *gin.Context) {
@@ -137,6 +304,10 @@ func (h *LogHandler) DeleteLog(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteLog(id); err != nil {
+		if errors.Is(err, repositories.ErrLogNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
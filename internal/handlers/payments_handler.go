@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/middleware"
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+	"oxo_game/internal/services"
+)
+
+type PaymentsHandler struct {
+	paymentService services.PaymentService
+}
+
+func NewPaymentsHandler(paymentService services.PaymentService) *PaymentsHandler {
+	return &PaymentsHandler{
+		paymentService: paymentService,
+	}
+}
+
+type topUpRequest struct {
+	PlayerID int          `json:"player_id"`
+	Method   string       `json:"method"`
+	Amount   models.Money `json:"amount"`
+	Details  string       `json:"details"`
+}
+
+func (h *PaymentsHandler) TopUp(c *gin.Context) {
+	var req topUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	playerID, ok := actingPlayer(c, req.PlayerID)
+	if !ok {
+		return
+	}
+
+	payment, err := h.paymentService.TopUp(playerID, req.Method, req.Amount, req.Details)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPaymentAmount), errors.Is(err, services.ErrUnsupportedPaymentMethod):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrPlayerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPaymentDeclined):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTopUpsDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment)
+}
+
+func (h *PaymentsHandler) GetPaymentByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		return
+	}
+
+	payment, err := h.paymentService.GetPaymentByID(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !middleware.CanActFor(c, payment.PlayerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "players can only see their own payments"})
+		return
+	}
+	c.JSON(http.StatusOK, payment)
+}
+
+// ListPayments lists the payments of the player_id parameter, which defaults
+// to the authenticated player.
+func (h *PaymentsHandler) ListPayments(c *gin.Context) {
+	var requested int
+	if v := c.Query("player_id"); v != "" {
+		var err error
+		if requested, err = strconv.Atoi(v); err != nil || requested <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+			return
+		}
+	}
+	playerID, ok := actingPlayer(c, requested)
+	if !ok {
+		return
+	}
+
+	payments, err := h.paymentService.ListPaymentsByPlayer(playerID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPlayerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, payments)
+}
+
+func (h *PaymentsHandler) ListMethods(c *gin.Context) {
+	c.JSON(http.StatusOK, h.paymentService.Methods())
+}
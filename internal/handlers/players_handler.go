@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"oxo_game/internal/middleware"
 	"oxo_game/internal/models"
 	"oxo_game/internal/services"
 
@@ -35,7 +37,7 @@ func (h *PlayersHandler) GetPlayerByID(c *gin.Context) {
 	}
 	player, err := h.service.GetPlayerByID(id)
 	if err != nil {
-		if err == services.ErrPlayerNotFound {
+		if errors.Is(err, services.ErrPlayerNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
 			return
 		}
@@ -45,14 +47,39 @@ func (h *PlayersHandler) GetPlayerByID(c *gin.Context) {
 	c.JSON(http.StatusOK, player)
 }
 
+// playerRequest is the body of a player create or update. The level is given
+// by level_id; an embedded level object is still accepted for its ID.
+type playerRequest struct {
+	models.Player
+	LevelID *int `json:"level_id"`
+}
+
+// player returns the requested player, with a level that only has an ID.
+func (r playerRequest) player() models.Player {
+	player := r.Player
+	switch {
+	case r.LevelID != nil && *r.LevelID == 0:
+		player.Level = nil
+	case r.LevelID != nil:
+		player.Level = &models.Level{ID: *r.LevelID}
+	case player.Level != nil:
+		player.Level = &models.Level{ID: player.Level.ID}
+	}
+	return player
+}
+
 func (h *PlayersHandler) CreatePlayer(c *gin.Context) {
-	var player models.Player
-	if err := c.BindJSON(&player); err != nil {
+	var req playerRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	id, err := h.service.CreatePlayer(player)
+	id, err := h.service.CreatePlayer(req.player())
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidRole) || errors.Is(err, services.ErrUnknownLevel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -65,16 +92,23 @@ func (h *PlayersHandler) UpdatePlayer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	var updatedPlayer models.Player
-	if err := c.BindJSON(&updatedPlayer); err != nil {
+	var req playerRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
-	if err := h.service.UpdatePlayer(id, updatedPlayer); err != nil {
-		if err == services.ErrPlayerNotFound {
+	// Only admins choose a player's level; anyone else keeps the stored one
+	caller, _ := middleware.CurrentPlayer(c)
+	setLevel := caller != nil && caller.HasRole(models.RoleAdmin)
+	if err := h.service.UpdatePlayer(id, req.player(), setLevel); err != nil {
+		if errors.Is(err, services.ErrPlayerNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, services.ErrUnknownLevel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -88,7 +122,7 @@ func (h *PlayersHandler) DeletePlayer(c *gin.Context) {
 		return
 	}
 	if err := h.service.DeletePlayer(id); err != nil {
-		if err == services.ErrPlayerNotFound {
+		if errors.Is(err, services.ErrPlayerNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
 			return
 		}
@@ -97,3 +131,65 @@ func (h *PlayersHandler) DeletePlayer(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "player deleted successfully"})
 }
+
+// SetPlayerRole gives the player the role in the body.
+func (h *PlayersHandler) SetPlayerRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	player, err := h.service.SetPlayerRole(id, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRole):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPlayerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, player)
+}
+
+// AwardXP adds the xp in the body, negative for a penalty, to the player's
+// experience and responds with the player at their new level.
+func (h *PlayersHandler) AwardXP(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player ID"})
+		return
+	}
+	var req struct {
+		XP     int64  `json:"xp"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.XP == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "xp must be a non-zero whole number"})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "awarded by an admin"
+	}
+
+	player, err := h.service.AwardXP(id, req.XP, req.Reason)
+	if err != nil {
+		if errors.Is(err, services.ErrPlayerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, player)
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
 	"oxo_game/internal/services"
 )
 
@@ -39,7 +41,7 @@ func (h *ReservationHandler) ListReservations(c *gin.Context) {
 
 	var date time.Time
 	if dateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", dateParam)
+		parsedDate, err := parseReservationDate(dateParam)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date parameter (format: yyyy-mm-dd)"})
 			return
@@ -49,15 +51,15 @@ func (h *ReservationHandler) ListReservations(c *gin.Context) {
 
 	var limit int
 	if limitParam != "" {
-		limit, err := strconv.Atoi(limitParam)
+		n, err := strconv.Atoi(limitParam)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
 			return
 		}
-		limit = limit
+		limit = n
 	}
 
-	reservations := h.reservationService.ListReservationsByRoomAndDate(roomID, date)
+	reservations := h.reservationService.ListReservations(roomID, date)
 
 	if limit > 0 && limit < len(reservations) {
 		reservations = reservations[:limit]
@@ -66,18 +68,255 @@ func (h *ReservationHandler) ListReservations(c *gin.Context) {
 	c.JSON(http.StatusOK, reservations)
 }
 
+// reservationRequest is the body of a new or rescheduled reservation. Date is
+// yyyy-mm-dd or an RFC 3339 timestamp whose date is used.
+type reservationRequest struct {
+	RoomID   int    `json:"room_id"`
+	Date     string `json:"date"`
+	Time     string `json:"time"`
+	PlayerID int    `json:"player_id"`
+}
+
 func (h *ReservationHandler) CreateReservation(c *gin.Context) {
-	var reservation models.Reservation
-	if err := c.ShouldBindJSON(&reservation); err != nil {
+	var req reservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
+	date, err := parseReservationDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date (format: yyyy-mm-dd)"})
+		return
+	}
+
+	playerID, ok := actingPlayer(c, req.PlayerID)
+	if !ok {
+		return
+	}
 
-	id, err := h.reservationService.CreateReservation(reservation.RoomID, reservation.Date, reservation.Time, reservation.PlayerID)
+	id, err := h.reservationService.CreateReservation(req.RoomID, date, req.Time, playerID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondReservationError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
+
+func (h *ReservationHandler) GetReservationByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reservation ID"})
+		return
+	}
+
+	reservation, err := h.reservationService.GetReservationByID(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (h *ReservationHandler) ConfirmReservation(c *gin.Context) {
+	h.changeReservation(c, h.reservationService.ConfirmReservation)
+}
+
+func (h *ReservationHandler) CancelReservation(c *gin.Context) {
+	h.changeReservation(c, h.reservationService.CancelReservation)
+}
+
+func (h *ReservationHandler) CheckIn(c *gin.Context) {
+	h.changeReservation(c, h.reservationService.CheckIn)
+}
+
+func (h *ReservationHandler) RescheduleReservation(c *gin.Context) {
+	var req reservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+	date, err := parseReservationDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date (format: yyyy-mm-dd)"})
+		return
+	}
+	h.changeReservation(c, func(id int) (*models.Reservation, error) {
+		return h.reservationService.RescheduleReservation(id, date, req.Time)
+	})
+}
+
+// reservationSeriesRequest is the body of a new reservation series. Rule is an
+// RRULE such as FREQ=WEEKLY;BYDAY=TU;COUNT=12 and Date is the first day it
+// may fall on. With AllOrNothing set, one conflicting occurrence rejects the
+// whole series.
+type reservationSeriesRequest struct {
+	reservationRequest
+	Rule         string `json:"rule"`
+	AllOrNothing bool   `json:"all_or_nothing"`
+}
+
+// CreateReservationSeries books every occurrence of a recurring reservation
+// and reports the occurrences that could not be booked.
+func (h *ReservationHandler) CreateReservationSeries(c *gin.Context) {
+	var req reservationSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+	date, err := parseReservationDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date (format: yyyy-mm-dd)"})
+		return
+	}
+
+	playerID, ok := actingPlayer(c, req.PlayerID)
+	if !ok {
+		return
+	}
+
+	result, err := h.reservationService.CreateReservationSeries(req.RoomID, date, req.Time, playerID, req.Rule, req.AllOrNothing)
+	if errors.Is(err, services.ErrSeriesConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": result.Conflicts})
+		return
+	}
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *ReservationHandler) GetReservationSeries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid series ID"})
+		return
+	}
+
+	result, err := h.reservationService.GetReservationSeries(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CancelReservationSeries cancels the occurrences of a series that have not
+// started yet. Only the series' player, an operator or an admin may.
+func (h *ReservationHandler) CancelReservationSeries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid series ID"})
+		return
+	}
+
+	series, err := h.reservationService.GetReservationSeries(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	if _, ok := actingPlayer(c, series.Series.PlayerID); !ok {
+		return
+	}
+
+	result, err := h.reservationService.CancelReservationSeries(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRoomAvailability lists the free and booked slots of a room for each day
+// from the from to the to query parameter, inclusive. Both default to today.
+func (h *ReservationHandler) GetRoomAvailability(c *gin.Context) {
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid room ID"})
+		return
+	}
+
+	from := time.Now().UTC()
+	if fromParam := c.Query("from"); fromParam != "" {
+		if from, err = parseReservationDate(fromParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter (format: yyyy-mm-dd)"})
+			return
+		}
+	}
+	to := from
+	if toParam := c.Query("to"); toParam != "" {
+		if to, err = parseReservationDate(toParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter (format: yyyy-mm-dd)"})
+			return
+		}
+	}
+
+	availability, err := h.reservationService.GetAvailability(roomID, from, to)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+// changeReservation applies change to the reservation named in the path and
+// responds with the updated reservation. Only the reservation's player, an
+// operator or an admin may change it.
+func (h *ReservationHandler) changeReservation(c *gin.Context, change func(id int) (*models.Reservation, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reservation ID"})
+		return
+	}
+
+	reservation, err := h.reservationService.GetReservationByID(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	if _, ok := actingPlayer(c, reservation.PlayerID); !ok {
+		return
+	}
+
+	reservation, err = change(id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+func respondReservationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReservation), errors.Is(err, services.ErrSlotEnded),
+		errors.Is(err, services.ErrOutsideOpeningHours), errors.Is(err, services.ErrInvalidDateRange),
+		errors.Is(err, services.ErrInvalidRecurrence):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrReservationNotFound), errors.Is(err, repositories.ErrRoomNotFound),
+		errors.Is(err, repositories.ErrPlayerNotFound), errors.Is(err, repositories.ErrReservationSeriesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomUnavailable), errors.Is(err, services.ErrReservationConflict),
+		errors.Is(err, services.ErrRoomFull), errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrCheckInClosed), errors.Is(err, services.ErrWeeklyLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseReservationDate parses a yyyy-mm-dd date, or takes the UTC date of an
+// RFC 3339 timestamp.
+func parseReservationDate(s string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", s); err == nil {
+		return date, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
 	"oxo_game/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -50,9 +52,9 @@ func (h *RoomsHandler) CreateRoom(c *gin.Context) {
 		return
 	}
 
-	id, err := h.service.CreateRoom(room.Name, room.Description)
+	id, err := h.service.CreateRoom(room)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondRoomError(c, err)
 		return
 	}
 
@@ -72,8 +74,8 @@ func (h *RoomsHandler) UpdateRoom(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateRoom(id, room.Name, room.Description); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateRoom(id, room); err != nil {
+		respondRoomError(c, err)
 		return
 	}
 
@@ -88,9 +90,102 @@ func (h *RoomsHandler) DeleteRoom(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteRoom(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondRoomError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// SetRoomStatus changes a room's status to the status in the body.
+func (h *RoomsHandler) SetRoomStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid room ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	room, err := h.service.SetRoomStatus(id, req.Status)
+	if err != nil {
+		respondRoomError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, room)
+}
+
+func (h *RoomsHandler) GetOccupancy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid room ID"})
+		return
+	}
+
+	occupancy, err := h.service.GetOccupancy(id)
+	if err != nil {
+		respondRoomError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, occupancy)
+}
+
+func (h *RoomsHandler) EnterRoom(c *gin.Context) {
+	h.moveOccupant(c, h.service.EnterRoom)
+}
+
+func (h *RoomsHandler) ExitRoom(c *gin.Context) {
+	h.moveOccupant(c, h.service.ExitRoom)
+}
+
+// moveOccupant moves the player in the body into or out of the room named in
+// the path and responds with the room's occupancy.
+func (h *RoomsHandler) moveOccupant(c *gin.Context, move func(roomID, playerID int) (*services.RoomOccupancy, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid room ID"})
+		return
+	}
+
+	var req struct {
+		PlayerID int `json:"player_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	playerID, ok := actingPlayer(c, req.PlayerID)
+	if !ok {
+		return
+	}
+
+	occupancy, err := move(id, playerID)
+	if err != nil {
+		respondRoomError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, occupancy)
+}
+
+func respondRoomError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRoom), errors.Is(err, services.ErrInvalidRoomStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrRoomNotFound), errors.Is(err, repositories.ErrPlayerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidRoomTransition), errors.Is(err, services.ErrRoomOccupied),
+		errors.Is(err, services.ErrRoomAtCapacity), errors.Is(err, services.ErrCapacityBelowOccupancy),
+		errors.Is(err, services.ErrRoomNotOpen),
+		errors.Is(err, services.ErrNotInRoom), errors.Is(err, repositories.ErrPlayerAlreadyInRoom):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"oxo_game/internal/models"
+	"oxo_game/internal/services"
+)
+
+// playerKey is the gin context key the authenticated player is stored under.
+const playerKey = "player"
+
+// Authenticate attaches the player named by the request's
+// "Authorization: Bearer <token>" header to the context. Requests without the
+// header carry on anonymously; an invalid or expired token is rejected.
+func Authenticate(auth services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := BearerToken(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		player, err := auth.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidToken) {
+				unauthorized(c, err.Error())
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(playerKey, player)
+		c.Next()
+	}
+}
+
+// RequireAuth rejects requests that are not authenticated.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := CurrentPlayer(c); !ok {
+			unauthorized(c, "authentication required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests unless they are authenticated as a player with
+// one of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		player, ok := CurrentPlayer(c)
+		if !ok {
+			unauthorized(c, "authentication required")
+			return
+		}
+		if !player.HasRole(roles...) {
+			forbidden(c, fmt.Sprintf("requires the %s role", strings.Join(roles, " or ")))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelf rejects requests unless they are authenticated as the player
+// whose ID is in the path parameter param, or as a player with one of roles.
+func RequireSelf(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		player, ok := CurrentPlayer(c)
+		if !ok {
+			unauthorized(c, "authentication required")
+			return
+		}
+		if id, err := strconv.Atoi(c.Param(param)); (err != nil || id != player.ID) && !player.HasRole(roles...) {
+			forbidden(c, "players can only access their own account")
+			return
+		}
+		c.Next()
+	}
+}
+
+// CanActFor reports whether the request may act on behalf of the player with
+// the given ID: it is authenticated as that player, or as an operator or
+// admin.
+func CanActFor(c *gin.Context, playerID int) bool {
+	player, ok := CurrentPlayer(c)
+	return ok && (player.ID == playerID || player.HasRole(models.RoleOperator, models.RoleAdmin))
+}
+
+// CurrentPlayer returns the player the request is authenticated as.
+func CurrentPlayer(c *gin.Context) (*models.Player, bool) {
+	value, ok := c.Get(playerKey)
+	if !ok {
+		return nil, false
+	}
+	player, ok := value.(*models.Player)
+	return player, ok
+}
+
+// BearerToken returns the token in the request's Authorization header.
+func BearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", "Bearer")
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+func forbidden(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+}
@@ -1,10 +1,308 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// LogAction is what a game log entry records. Only the actions below are
+// accepted.
+type LogAction string
+
+// Actions logged for player accounts.
+const (
+	LogActionRegister LogAction = "Register"
+	LogActionLogin    LogAction = "Login"
+	LogActionLogout   LogAction = "Logout"
+)
+
+// LogActionLevelChanged is logged when a player's experience moves them to
+// another level.
+const LogActionLevelChanged LogAction = "Level Changed"
+
+// Actions logged when players enter and leave rooms.
+const (
+	LogActionEnterRoom LogAction = "Enter Room"
+	LogActionExitRoom  LogAction = "Exit Room"
+	// LogActionRoomStatusChanged is logged for every room status change,
+	// including a room filling up or emptying.
+	LogActionRoomStatusChanged LogAction = "Room Status Changed"
+)
+
+// Actions logged for challenges.
+const (
+	LogActionParticipateChallenge LogAction = "Participate in Challenge"
+	LogActionChallengeResult      LogAction = "Challenge Result"
+)
+
+// Actions logged for reservation changes.
+const (
+	LogActionReservationCreated     LogAction = "Reservation Created"
+	LogActionReservationConfirmed   LogAction = "Reservation Confirmed"
+	LogActionReservationRescheduled LogAction = "Reservation Rescheduled"
+	LogActionReservationCancelled   LogAction = "Reservation Cancelled"
+	LogActionReservationCompleted   LogAction = "Reservation Completed"
+	LogActionCheckIn                LogAction = "Check In"
+	LogActionNoShow                 LogAction = "No Show"
+)
+
+// LogActions lists every known action.
+var LogActions = []LogAction{
+	LogActionRegister, LogActionLogin, LogActionLogout, LogActionLevelChanged,
+	LogActionEnterRoom, LogActionExitRoom, LogActionRoomStatusChanged,
+	LogActionParticipateChallenge, LogActionChallengeResult,
+	LogActionReservationCreated, LogActionReservationConfirmed, LogActionReservationRescheduled,
+	LogActionReservationCancelled, LogActionReservationCompleted, LogActionCheckIn, LogActionNoShow,
+}
+
+// Valid reports whether a is one of the known actions.
+func (a LogAction) Valid() bool {
+	for _, action := range LogActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 type Log struct {
-	ID        int    `json:"id"`
-	PlayerID  int    `json:"player_id"`
-	Action    string `json:"action"`
-	Timestamp int64  `json:"timestamp"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID        int        `json:"id"`
+	PlayerID  int        `json:"player_id"`
+	Action    LogAction  `json:"action"`
+	Details   LogDetails `json:"details"`
+	Timestamp int64      `json:"timestamp"`
+	CreatedAt int64      `json:"created_at"`
+	UpdatedAt int64      `json:"updated_at"`
+}
+
+var ErrInvalidLogDetails = errors.New("invalid log details")
+
+// MaxLogDetailLength is the longest string a log detail can hold.
+const MaxLogDetailLength = 255
+
+// LogDetailNote is the free-text detail every action accepts.
+const LogDetailNote = "note"
+
+// LogDetails holds the structured details of a log entry, such as the room a
+// player entered. The fields each action takes are given by
+// LogDetailSchemas. Values are scalars kept as their JSON types, so numbers
+// are float64.
+type LogDetails map[string]any
+
+// UnmarshalJSON decodes a JSON object. A plain string, which older clients
+// send, becomes the note.
+func (d *LogDetails) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var note string
+		if err := json.Unmarshal(data, &note); err != nil {
+			return err
+		}
+		*d = LogDetails{LogDetailNote: note}
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*d = fields
+	return nil
+}
+
+// LogDetailKind is the type of a log detail.
+type LogDetailKind string
+
+const (
+	LogDetailString  LogDetailKind = "string"
+	LogDetailInteger LogDetailKind = "integer"
+	LogDetailBoolean LogDetailKind = "boolean"
+	// LogDetailMoney is an amount written as a decimal string, e.g. "10.00".
+	LogDetailMoney LogDetailKind = "money"
+)
+
+// LogDetailField describes one field of a LogDetailSchema.
+type LogDetailField struct {
+	Kind     LogDetailKind `json:"kind"`
+	Required bool          `json:"required,omitempty"`
+}
+
+// LogDetailSchema maps the fields an action's details may have to their
+// types. Every action also takes an optional note.
+type LogDetailSchema map[string]LogDetailField
+
+var (
+	roomDetails = LogDetailSchema{
+		"room_id":   {Kind: LogDetailInteger, Required: true},
+		"room_name": {Kind: LogDetailString},
+		"occupants": {Kind: LogDetailInteger},
+		"capacity":  {Kind: LogDetailInteger},
+	}
+	reservationDetails = LogDetailSchema{
+		"reservation_id": {Kind: LogDetailInteger, Required: true},
+		"room_id":        {Kind: LogDetailInteger, Required: true},
+		"date":           {Kind: LogDetailString},
+		"time":           {Kind: LogDetailString},
+		"series_id":      {Kind: LogDetailInteger},
+		"from_status":    {Kind: LogDetailString},
+	}
+)
+
+// LogDetailSchemas gives the schema of each action's details.
+var LogDetailSchemas = map[LogAction]LogDetailSchema{
+	LogActionRegister: {
+		"username": {Kind: LogDetailString},
+		"role":     {Kind: LogDetailString},
+	},
+	LogActionLogin:  {},
+	LogActionLogout: {},
+	LogActionLevelChanged: {
+		"xp":            {Kind: LogDetailInteger, Required: true},
+		"level_id":      {Kind: LogDetailInteger},
+		"level":         {Kind: LogDetailString},
+		"from_level_id": {Kind: LogDetailInteger},
+		"from_level":    {Kind: LogDetailString},
+		"reason":        {Kind: LogDetailString},
+	},
+	LogActionEnterRoom: roomDetails,
+	LogActionExitRoom: roomDetails.with(LogDetailSchema{
+		"stayed_seconds": {Kind: LogDetailInteger},
+	}),
+	LogActionRoomStatusChanged: {
+		"room_id":     {Kind: LogDetailInteger, Required: true},
+		"status":      {Kind: LogDetailString, Required: true},
+		"from_status": {Kind: LogDetailString},
+	},
+	LogActionParticipateChallenge: {
+		"challenge_id":  {Kind: LogDetailInteger, Required: true},
+		"definition_id": {Kind: LogDetailInteger},
+		"fee":           {Kind: LogDetailMoney, Required: true},
+	},
+	LogActionChallengeResult: {
+		"challenge_id":  {Kind: LogDetailInteger, Required: true},
+		"definition_id": {Kind: LogDetailInteger},
+		"won":           {Kind: LogDetailBoolean, Required: true},
+		"jackpot":       {Kind: LogDetailMoney},
+	},
+	LogActionReservationCreated:   reservationDetails,
+	LogActionReservationConfirmed: reservationDetails,
+	LogActionReservationRescheduled: reservationDetails.with(LogDetailSchema{
+		"from_date": {Kind: LogDetailString, Required: true},
+		"from_time": {Kind: LogDetailString, Required: true},
+	}),
+	LogActionReservationCancelled: reservationDetails,
+	LogActionReservationCompleted: reservationDetails,
+	LogActionCheckIn:              reservationDetails,
+	LogActionNoShow:               reservationDetails,
+}
+
+// with returns a copy of s with the fields of extra added.
+func (s LogDetailSchema) with(extra LogDetailSchema) LogDetailSchema {
+	merged := make(LogDetailSchema, len(s)+len(extra))
+	for name, field := range s {
+		merged[name] = field
+	}
+	for name, field := range extra {
+		merged[name] = field
+	}
+	return merged
+}
+
+// Field returns the schema of the named field.
+func (s LogDetailSchema) Field(name string) (LogDetailField, bool) {
+	if name == LogDetailNote {
+		return LogDetailField{Kind: LogDetailString}, true
+	}
+	field, ok := s[name]
+	return field, ok
+}
+
+// ValidateDetails checks details against the action's schema and returns
+// them with every value converted to its JSON type. Go integers and Money
+// are accepted as well, for the services that publish events.
+func (a LogAction) ValidateDetails(details LogDetails) (LogDetails, error) {
+	schema, ok := LogDetailSchemas[a]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidLogDetails, a)
+	}
+	for name, field := range schema {
+		if _, ok := details[name]; field.Required && !ok {
+			return nil, fmt.Errorf("%w: %s needs %s", ErrInvalidLogDetails, a, name)
+		}
+	}
+
+	valid := make(LogDetails, len(details))
+	for name, value := range details {
+		field, ok := schema.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s does not take %s", ErrInvalidLogDetails, a, name)
+		}
+		v, err := field.Kind.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %v", ErrInvalidLogDetails, name, err)
+		}
+		valid[name] = v
+	}
+	return valid, nil
+}
+
+func (k LogDetailKind) convert(value any) (any, error) {
+	switch k {
+	case LogDetailString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len(s) > MaxLogDetailLength {
+			return nil, fmt.Errorf("must be at most %d bytes", MaxLogDetailLength)
+		}
+		return s, nil
+	case LogDetailInteger:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+				return v, nil
+			}
+		}
+		return nil, errors.New("must be an integer")
+	case LogDetailBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case LogDetailMoney:
+		switch v := value.(type) {
+		case Money:
+			return v.String(), nil
+		case string:
+			m, err := ParseMoney(v)
+			if err != nil {
+				return nil, err
+			}
+			return m.String(), nil
+		}
+		return nil, errors.New("must be a decimal string")
+	}
+	return nil, fmt.Errorf("has unknown kind %q", k)
+}
+
+// LogDetailText returns a detail value as the text logs are searched by:
+// integers without a decimal point, booleans as "true" or "false".
+func LogDetailText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
 }
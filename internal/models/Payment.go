@@ -1,9 +1,26 @@
 package models
 
+// Payment types describe why a player's balance changed.
+const (
+	PaymentTypeTopUp         = "top_up"
+	PaymentTypeChallengeFee  = "challenge_fee"
+	PaymentTypeJackpotPayout = "jackpot_payout"
+	PaymentTypeRefund        = "refund"
+	PaymentTypeAdjustment    = "adjustment"
+)
+
+// PaymentMethodWallet is the method of payments moved within the game rather
+// than through an external payment provider.
+const PaymentMethodWallet = "wallet"
+
+// Payment records one change to a player's balance. Amount is positive when
+// the balance was credited and negative when it was debited.
 type Payment struct {
-	ID        int     `json:"id"`
-	Method    string  `json:"method"`
-	Amount    float64 `json:"amount"`
-	Details   string  `json:"details"`
-	CreatedAt int64   `json:"created_at"`
+	ID        int    `json:"id"`
+	PlayerID  int    `json:"player_id"`
+	Type      string `json:"type"`
+	Method    string `json:"method"`
+	Amount    Money  `json:"amount"`
+	Details   string `json:"details"`
+	CreatedAt int64  `json:"created_at"`
 }
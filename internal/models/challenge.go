@@ -4,10 +4,12 @@ import "time"
 
 // Challenge represents a game challenge entity.
 type Challenge struct {
-	ID        int       `json:"id"`
-	PlayerID  int       `json:"player_id"`
-	CreatedAt time.Time `json:"created_at"`
-	Won       bool      `json:"won"`
+	ID       int `json:"id"`
+	PlayerID int `json:"player_id"`
+	// DefinitionID is the ChallengeDefinition the attempt was at.
+	DefinitionID int       `json:"definition_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Won          bool      `json:"won"`
 }
 
 // NewChallenge creates a new Challenge instance with initialized fields.
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ChallengeDefinition is a challenge players can join: what it costs, the
+// odds of winning the jackpot and how often a player may take part.
+type ChallengeDefinition struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Fee is charged for every attempt.
+	Fee Money `json:"fee"`
+	// WinProbability is the chance, from 0 to 1, that an attempt wins the
+	// jackpot.
+	WinProbability float64 `json:"win_probability"`
+	// DurationSeconds is how long an attempt lasts.
+	DurationSeconds int `json:"duration_seconds"`
+	// CooldownSeconds is how long a player waits after an attempt ends
+	// before the next one.
+	CooldownSeconds int `json:"cooldown_seconds"`
+	// MaxPerDay caps a player's attempts per UTC day; 0 is no cap.
+	MaxPerDay int `json:"max_per_day"`
+	// MinLevelOrder is the lowest level order a player must be at to join;
+	// 0 lets every player join, with or without a level.
+	MinLevelOrder int `json:"min_level_order"`
+	// ActiveFrom and ActiveUntil bound when the challenge can be joined.
+	// Either may be nil for an open end.
+	ActiveFrom  *time.Time `json:"active_from"`
+	ActiveUntil *time.Time `json:"active_until"`
+}
+
+// ActiveAt reports whether the challenge can be joined at t.
+func (d *ChallengeDefinition) ActiveAt(t time.Time) bool {
+	if d.ActiveFrom != nil && t.Before(*d.ActiveFrom) {
+		return false
+	}
+	if d.ActiveUntil != nil && !t.Before(*d.ActiveUntil) {
+		return false
+	}
+	return true
+}
+
+// Duration returns how long an attempt lasts.
+func (d *ChallengeDefinition) Duration() time.Duration {
+	return time.Duration(d.DurationSeconds) * time.Second
+}
+
+// Cooldown returns how long a player waits after an attempt ends.
+func (d *ChallengeDefinition) Cooldown() time.Duration {
+	return time.Duration(d.CooldownSeconds) * time.Second
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Credentials are the username and bcrypt password hash a player logs in
+// with.
+type Credentials struct {
+	PlayerID     int       `json:"player_id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session is a logged in player. Only the SHA-256 hash of the session token
+// is stored; the token itself is handed to the player once, at login.
+type Session struct {
+	TokenHash string    `json:"-"`
+	PlayerID  int       `json:"player_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the session has expired at now.
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package models
+
+// JackpotPool is the prize pool that grows with every challenge entry fee and
+// is paid out in full to the next jackpot winner.
+type JackpotPool struct {
+	ID        int   `json:"id"`
+	Balance   Money `json:"balance"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// JackpotPayout records a jackpot paid to a player.
+type JackpotPayout struct {
+	ID          int   `json:"id"`
+	PlayerID    int   `json:"player_id"`
+	ChallengeID int   `json:"challenge_id"`
+	Amount      Money `json:"amount"`
+	CreatedAt   int64 `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "fmt"
+
+// Ledger transaction kinds.
+const (
+	LedgerKindTopUp         = "top_up"
+	LedgerKindChallengeFee  = "challenge_fee"
+	LedgerKindJackpotPayout = "jackpot_payout"
+	LedgerKindRefund        = "refund"
+	LedgerKindAdjustment    = "adjustment"
+)
+
+// House accounts that balance the player accounts in the ledger.
+const (
+	AccountJackpot     = "house:jackpot"
+	AccountRevenue     = "house:revenue"
+	AccountAdjustments = "house:adjustments"
+)
+
+// PlayerAccount returns the ledger account holding a player's balance.
+func PlayerAccount(playerID int) string {
+	return fmt.Sprintf("player:%d", playerID)
+}
+
+// ExternalAccount returns the ledger account money from a payment method
+// enters the game through.
+func ExternalAccount(method string) string {
+	return "external:" + method
+}
+
+// LedgerTransaction is one balanced movement of money between accounts. Its
+// entries always sum to zero.
+type LedgerTransaction struct {
+	ID        int           `json:"id"`
+	Kind      string        `json:"kind"`
+	Memo      string        `json:"memo"`
+	Reference string        `json:"reference"`
+	CreatedAt int64         `json:"created_at"`
+	Entries   []LedgerEntry `json:"entries"`
+}
+
+// LedgerEntry changes the balance of one account. Amount is positive when the
+// account is credited and negative when it is debited.
+type LedgerEntry struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	Account       string `json:"account"`
+	Amount        Money  `json:"amount"`
+}
+
+// AmountFor returns the net amount the transaction moved in or out of account.
+func (t *LedgerTransaction) AmountFor(account string) Money {
+	var amount Money
+	for _, entry := range t.Entries {
+		if entry.Account == account {
+			amount = amount.Add(entry.Amount)
+		}
+	}
+	return amount
+}
@@ -1,6 +1,54 @@
 package models
 
+// Level is a rung on the progression ladder. Levels are ranked by Order, and
+// MinXP is the experience a player needs to reach the level; thresholds rise
+// with the order.
 type Level struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID    int        `json:"id"`
+	Name  string     `json:"name"`
+	Order int        `json:"order"`
+	MinXP int64      `json:"min_xp"`
+	Perks LevelPerks `json:"perks"`
+}
+
+// LevelPerks are the gameplay rules for players at a level. Zero values
+// leave the standard rules in place.
+type LevelPerks struct {
+	// RoomIDs lists the rooms players at the level may reserve; empty
+	// allows every room.
+	RoomIDs []int `json:"room_ids"`
+	// MaxWeeklyReservations caps the active reservations a player may hold
+	// in one week, Monday to Sunday; 0 is no cap.
+	MaxWeeklyReservations int `json:"max_weekly_reservations"`
+	// ChallengeFee caps challenge entry fees; challenges that cost less
+	// keep their own fee.
+	ChallengeFee *Money `json:"challenge_fee"`
+	// ChallengeCooldownSeconds replaces the standard wait between
+	// challenges.
+	ChallengeCooldownSeconds int `json:"challenge_cooldown_seconds"`
+}
+
+// MayReserve reports whether players with the perks may reserve the room.
+func (p LevelPerks) MayReserve(roomID int) bool {
+	if len(p.RoomIDs) == 0 {
+		return true
+	}
+	for _, id := range p.RoomIDs {
+		if id == roomID {
+			return true
+		}
+	}
+	return false
+}
+
+// LevelForXP returns the highest level in levels, which are sorted by Order,
+// whose threshold xp reaches, or nil when it reaches none.
+func LevelForXP(levels []*Level, xp int64) *Level {
+	var reached *Level
+	for _, level := range levels {
+		if level.MinXP <= xp {
+			reached = level
+		}
+	}
+	return reached
 }
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// centsPerUnit is the number of minor units in one unit of currency. Amounts
+// are stored with two decimal places, matching the DECIMAL columns.
+const centsPerUnit = 100
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money is an exact amount of the game's single currency, held as a whole
+// number of cents. It marshals to JSON as a decimal string such as "20.01"
+// and can be scanned from and written to DECIMAL columns. The zero value is
+// zero.
+type Money struct {
+	cents int64
+}
+
+// Cents returns an amount given in cents.
+func Cents(cents int64) Money {
+	return Money{cents: cents}
+}
+
+// ParseMoney parses a decimal amount such as "-20.01". More
+// than two decimal places is an error rather than being rounded.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	sign := int64(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > 2 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	units := int64(0)
+	if whole != "" {
+		var err error
+		if units, err = strconv.ParseInt(whole, 10, 64); err != nil || units > math.MaxInt64/centsPerUnit-1 {
+			return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+		}
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	return Cents(sign * (units*centsPerUnit + cents)), nil
+}
+
+// MustParseMoney is like ParseMoney but panics if s is not a valid amount. It
+// is meant for constants.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cents returns the amount in cents.
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) Money {
+	return Money{cents: m.cents + o.cents}
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return Money{cents: m.cents - o.cents}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{cents: -m.cents}
+}
+
+// MulRate returns m multiplied by rate, rounded half away from zero to the
+// nearest cent.
+func (m Money) MulRate(rate float64) Money {
+	return Money{cents: int64(math.Round(float64(m.cents) * rate))}
+}
+
+// Cmp compares m and o and returns -1, 0 or +1.
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.cents < o.cents:
+		return -1
+	case m.cents > o.cents:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) IsZero() bool     { return m.cents == 0 }
+func (m Money) IsPositive() bool { return m.cents > 0 }
+func (m Money) IsNegative() bool { return m.cents < 0 }
+
+// String formats the amount with two decimal places, e.g. "-20.01".
+func (m Money) String() string {
+	cents := m.cents
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/centsPerUnit, cents%centsPerUnit)
+}
+
+// MarshalJSON encodes the amount as a decimal string.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON decodes a decimal string. A bare JSON number is accepted as
+// well for older clients; it is parsed from its text, never through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value writes the amount as a decimal string, which DECIMAL columns store
+// exactly.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads an amount from a DECIMAL column. MySQL returns
+// DECIMAL values as text; SQLite stores them as REAL, which is rounded to the
+// nearest cent.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			// DECIMAL(12,4) and computed columns may carry extra places
+			f, ferr := strconv.ParseFloat(v, 64)
+			if ferr != nil {
+				return err
+			}
+			return m.Scan(f)
+		}
+		*m = parsed
+	case int64:
+		*m = Cents(v * centsPerUnit)
+	case float64:
+		*m = Cents(int64(math.Round(v * centsPerUnit)))
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidMoney, src)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in    string
+		cents int64
+	}{
+		{"20.01", 20_01},
+		{"20", 20_00},
+		{"20.1", 20_10},
+		{".5", 50},
+		{"5.", 5_00},
+		{"+3.00", 3_00},
+		{"-20.01", -20_01},
+		{"-0.99", -99},
+		{" 7.25 ", 7_25},
+		{"0", 0},
+	}
+	for _, test := range tests {
+		got, err := ParseMoney(test.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): unexpected error %v", test.in, err)
+			continue
+		}
+		if got.Cents() != test.cents {
+			t.Errorf("ParseMoney(%q): expected %d cents, got %d", test.in, test.cents, got.Cents())
+		}
+	}
+}
+
+func TestParseMoney_Invalid(t *testing.T) {
+	// 超过两位小数是错误，而不是四舍五入
+	for _, in := range []string{"", ".", "-", "1.001", "20.015", "1,00", "1e3", "abc", "--1", "1.-1", "99999999999999999999"} {
+		if got, err := ParseMoney(in); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q): expected ErrInvalidMoney, got %v (%s)", in, err, got)
+		}
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{20_01, "20.01"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{-20_01, "-20.01"},
+		{0, "0.00"},
+	}
+	for _, test := range tests {
+		if got := Cents(test.cents).String(); got != test.want {
+			t.Errorf("Cents(%d).String(): expected %q, got %q", test.cents, test.want, got)
+		}
+	}
+}
+
+func TestMoney_MulRateRounding(t *testing.T) {
+	tests := []struct {
+		cents int64
+		rate  float64
+		want  int64
+	}{
+		{20_01, 0.5, 10_01}, // 1000.5 rounds away from zero
+		{-20_01, 0.5, -10_01},
+		{1, 0.5, 1},
+		{-1, 0.5, -1},
+		{20_00, 0.25, 5_00},
+		{3, 0.1, 0},
+	}
+	for _, test := range tests {
+		if got := Cents(test.cents).MulRate(test.rate); got.Cents() != test.want {
+			t.Errorf("Cents(%d).MulRate(%v): expected %d cents, got %d", test.cents, test.rate, test.want, got.Cents())
+		}
+	}
+}
+
+func TestMoney_JSONRoundTrip(t *testing.T) {
+	for _, m := range []Money{Cents(20_01), Cents(-20_01), Cents(5), Cents(0)} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("Error marshalling %s: %v", m, err)
+		}
+		if string(data) != `"`+m.String()+`"` {
+			t.Errorf("Expected %s to marshal as a string, got %s", m, data)
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Error unmarshalling %s: %v", data, err)
+		}
+		if got != m {
+			t.Errorf("Expected %s after the round trip, got %s", m, got)
+		}
+	}
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	var body struct {
+		Amount Money  `json:"amount"`
+		Fee    *Money `json:"fee"`
+	}
+	// 旧客户端发送裸数字；null 保持零值
+	if err := json.Unmarshal([]byte(`{"amount": -12.5, "fee": null}`), &body); err != nil {
+		t.Fatalf("Error unmarshalling: %v", err)
+	}
+	if body.Amount != Cents(-12_50) || body.Fee != nil {
+		t.Errorf("Expected -12.50 and no fee, got %s and %v", body.Amount, body.Fee)
+	}
+
+	for _, data := range []string{`"1.001"`, `1.001`, `"abc"`, `true`, `"1e2"`} {
+		var m Money
+		if err := json.Unmarshal([]byte(data), &m); err == nil {
+			t.Errorf("Expected an error unmarshalling %s, got %s", data, m)
+		}
+	}
+}
+
+func TestMoney_SQLRoundTrip(t *testing.T) {
+	for _, m := range []Money{Cents(20_01), Cents(-20_01), Cents(5), Cents(0)} {
+		value, err := m.Value()
+		if err != nil {
+			t.Fatalf("Error writing %s: %v", m, err)
+		}
+		var got Money
+		if err := got.Scan(value); err != nil {
+			t.Fatalf("Error scanning %v: %v", value, err)
+		}
+		if got != m {
+			t.Errorf("Expected %s after the round trip, got %s", m, got)
+		}
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want Money
+	}{
+		// MySQL returns DECIMAL as text
+		{"text", []byte("20.01"), Cents(20_01)},
+		{"negative text", "-20.01", Cents(-20_01)},
+		{"extra places", "1.1250", Cents(1_13)},
+		{"negative extra places", "-1.1250", Cents(-1_13)},
+		// SQLite keeps DECIMAL as REAL or, for whole numbers, INTEGER
+		{"real", 20.01, Cents(20_01)},
+		{"negative real", -0.125, Cents(-13)},
+		{"integer", int64(20), Cents(20_00)},
+		{"null", nil, Money{}},
+	}
+	for _, test := range tests {
+		got := Cents(99)
+		if err := got.Scan(test.src); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: expected %s, got %s", test.name, test.want, got)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Expected ErrInvalidMoney scanning a bool, got %v", err)
+	}
+	if err := m.Scan("abc"); err == nil {
+		t.Errorf("Expected an error scanning %q", "abc")
+	}
+}
@@ -1,8 +1,44 @@
 package models
 
+// Roles a player can have. Operators run the rooms; admins manage the game.
+const (
+	RolePlayer   = "player"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// Player is a player account. Role is one of the Role constants; an empty role
+// is a plain player.
 type Player struct {
-	ID      int     `json:"id"`
-	Name    string  `json:"name"`
-	Level   *Level  `json:"level"`
-	Balance float64 `json:"balance"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Level   *Level `json:"level"`
+	Balance Money  `json:"balance"`
+	Role    string `json:"role"`
+	// XP is the experience the player has earned. Once a player has earned
+	// any, their level follows it.
+	XP int64 `json:"xp"`
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RolePlayer, RoleOperator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// HasRole reports whether the player has one of roles.
+func (p *Player) HasRole(roles ...string) bool {
+	role := p.Role
+	if role == "" {
+		role = RolePlayer
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
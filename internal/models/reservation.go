@@ -2,11 +2,54 @@ package models
 
 import "time"
 
+// Reservation statuses. A reservation starts pending, is confirmed, checked
+// in when the player arrives and completed once its slot is over. Pending and
+// confirmed reservations can be cancelled, and become no-shows when the slot
+// ends without a check-in.
+const (
+	ReservationStatusPending   = "pending"
+	ReservationStatusConfirmed = "confirmed"
+	ReservationStatusCheckedIn = "checked_in"
+	ReservationStatusCompleted = "completed"
+	ReservationStatusCancelled = "cancelled"
+	ReservationStatusNoShow    = "no_show"
+)
+
+// reservationTransitions lists the statuses each status can move to.
+var reservationTransitions = map[string][]string{
+	ReservationStatusPending:   {ReservationStatusConfirmed, ReservationStatusCancelled, ReservationStatusNoShow},
+	ReservationStatusConfirmed: {ReservationStatusCheckedIn, ReservationStatusCancelled, ReservationStatusNoShow},
+	ReservationStatusCheckedIn: {ReservationStatusCompleted},
+}
+
+// Reservation books a room for one slot. SeriesID is the recurring series the
+// reservation belongs to, or 0.
 type Reservation struct {
 	ID        int       `json:"id"`
 	RoomID    int       `json:"room_id"`
 	Date      time.Time `json:"date"`
 	Time      string    `json:"time"`
 	PlayerID  int       `json:"player_id"`
+	Status    string    `json:"status"`
+	SeriesID  int       `json:"series_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// CanTransitionTo reports whether the reservation may move to status.
+func (r *Reservation) CanTransitionTo(status string) bool {
+	for _, next := range reservationTransitions[r.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the reservation still holds its slot.
+func (r *Reservation) Active() bool {
+	switch r.Status {
+	case ReservationStatusPending, ReservationStatusConfirmed, ReservationStatusCheckedIn:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ReservationSeries is a recurring booking of the same room and time, applying
+// Rule (an RRULE such as FREQ=WEEKLY;BYDAY=TU;COUNT=12) from StartDate. Each
+// occurrence is stored as its own Reservation carrying the series' ID.
+type ReservationSeries struct {
+	ID        int       `json:"id"`
+	RoomID    int       `json:"room_id"`
+	PlayerID  int       `json:"player_id"`
+	Time      string    `json:"time"`
+	StartDate time.Time `json:"start_date"`
+	Rule      string    `json:"rule"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,8 +1,58 @@
 package models
 
+// Room statuses. A room is occupied while players are inside it; it becomes
+// occupied when the first player enters and available when the last leaves.
+const (
+	RoomStatusAvailable   = "available"
+	RoomStatusOccupied    = "occupied"
+	RoomStatusMaintenance = "maintenance"
+	RoomStatusClosed      = "closed"
+)
+
+// roomTransitions lists the statuses each room status can move to. Rooms are
+// only taken out of service while they are empty.
+var roomTransitions = map[string][]string{
+	RoomStatusAvailable:   {RoomStatusOccupied, RoomStatusMaintenance, RoomStatusClosed},
+	RoomStatusOccupied:    {RoomStatusAvailable},
+	RoomStatusMaintenance: {RoomStatusAvailable, RoomStatusClosed},
+	RoomStatusClosed:      {RoomStatusAvailable, RoomStatusMaintenance},
+}
+
+// Default room settings used when a room is created without them.
+const (
+	DefaultRoomCapacity    = 1
+	DefaultRoomSlotMinutes = 60
+	DefaultRoomOpensAt     = "09:00"
+	DefaultRoomClosesAt    = "22:00"
+)
+
 type Room struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
+	// Capacity is the number of players that can hold a reservation for the
+	// same slot.
+	Capacity int `json:"capacity"`
+	// SlotMinutes is how long a reservation lasts.
+	SlotMinutes int `json:"slot_minutes"`
+	// OpensAt and ClosesAt are the room's daily operating hours in UTC, as
+	// HH:MM. Every reservation starts and ends within them.
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+}
+
+// Bookable reports whether the room accepts new reservations.
+func (r *Room) Bookable() bool {
+	return r.Status != RoomStatusMaintenance && r.Status != RoomStatusClosed
+}
+
+// CanTransitionTo reports whether the room may move to status.
+func (r *Room) CanTransitionTo(status string) bool {
+	for _, next := range roomTransitions[r.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
 }
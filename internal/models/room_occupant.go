@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RoomOccupant records that a player is inside a room.
+type RoomOccupant struct {
+	RoomID    int       `json:"room_id"`
+	PlayerID  int       `json:"player_id"`
+	EnteredAt time.Time `json:"entered_at"`
+}
@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+var (
+	ErrCredentialsNotFound = errors.New("credentials not found")
+	ErrUsernameTaken       = errors.New("username is already taken")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// AuthRepository stores players' login credentials and their sessions.
+type AuthRepository interface {
+	// CreateCredentials stores a player's credentials. Usernames are unique;
+	// ErrUsernameTaken is returned for one already in use.
+	CreateCredentials(credentials *models.Credentials) error
+	GetCredentialsByUsername(username string) (*models.Credentials, error)
+	GetCredentialsByPlayerID(playerID int) (*models.Credentials, error)
+	CreateSession(session *models.Session) error
+	GetSession(tokenHash string) (*models.Session, error)
+	DeleteSession(tokenHash string) error
+	// DeleteExpiredSessions removes the sessions that expired by now and
+	// returns how many there were.
+	DeleteExpiredSessions(now time.Time) (int, error)
+	// DeletePlayerAuth removes a player's credentials and all their
+	// sessions. A player without any is not an error.
+	DeletePlayerAuth(playerID int) error
+}
+
+// InMemoryAuthRepository keeps credentials and sessions in memory.
+type InMemoryAuthRepository struct {
+	*authStore
+	journal *undoJournal
+}
+
+type authStore struct {
+	mu          sync.RWMutex
+	credentials map[int]models.Credentials
+	sessions    map[string]models.Session
+}
+
+// NewInMemoryAuthRepository creates a new InMemoryAuthRepository.
+func NewInMemoryAuthRepository() *InMemoryAuthRepository {
+	return &InMemoryAuthRepository{
+		authStore: &authStore{
+			credentials: make(map[int]models.Credentials),
+			sessions:    make(map[string]models.Session),
+		},
+	}
+}
+
+func (r *InMemoryAuthRepository) withJournal(j *undoJournal) AuthRepository {
+	return &InMemoryAuthRepository{authStore: r.authStore, journal: j}
+}
+
+// recordSession records how to put the session back as it is now, or remove
+// it if it does not exist yet. The caller holds the write lock.
+func (r *InMemoryAuthRepository) recordSession(tokenHash string) {
+	session, existed := r.sessions[tokenHash]
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.sessions[tokenHash] = session
+		} else {
+			delete(r.sessions, tokenHash)
+		}
+	})
+}
+
+// recordCredentials records how to put the player's credentials back as they
+// are now, or remove them if they do not exist yet. The caller holds the
+// write lock.
+func (r *InMemoryAuthRepository) recordCredentials(playerID int) {
+	credentials, existed := r.credentials[playerID]
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.credentials[playerID] = credentials
+		} else {
+			delete(r.credentials, playerID)
+		}
+	})
+}
+
+// CreateCredentials stores a player's credentials.
+func (r *InMemoryAuthRepository) CreateCredentials(credentials *models.Credentials) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.credentials {
+		if existing.Username == credentials.Username {
+			return ErrUsernameTaken
+		}
+	}
+	credentials.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	r.recordCredentials(credentials.PlayerID)
+	r.credentials[credentials.PlayerID] = *credentials
+	return nil
+}
+
+// GetCredentialsByUsername returns the credentials with the given username.
+func (r *InMemoryAuthRepository) GetCredentialsByUsername(username string) (*models.Credentials, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, credentials := range r.credentials {
+		if credentials.Username == username {
+			return &credentials, nil
+		}
+	}
+	return nil, ErrCredentialsNotFound
+}
+
+// GetCredentialsByPlayerID returns the credentials of the given player.
+func (r *InMemoryAuthRepository) GetCredentialsByPlayerID(playerID int) (*models.Credentials, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	credentials, ok := r.credentials[playerID]
+	if !ok {
+		return nil, ErrCredentialsNotFound
+	}
+	return &credentials, nil
+}
+
+// CreateSession stores a new session.
+func (r *InMemoryAuthRepository) CreateSession(session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordSession(session.TokenHash)
+	r.sessions[session.TokenHash] = *session
+	return nil
+}
+
+// GetSession returns the session with the given token hash.
+func (r *InMemoryAuthRepository) GetSession(tokenHash string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	session, ok := r.sessions[tokenHash]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// DeleteSession deletes the session with the given token hash.
+func (r *InMemoryAuthRepository) DeleteSession(tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[tokenHash]; !ok {
+		return ErrSessionNotFound
+	}
+	r.recordSession(tokenHash)
+	delete(r.sessions, tokenHash)
+	return nil
+}
+
+// DeleteExpiredSessions removes the sessions that expired by now.
+func (r *InMemoryAuthRepository) DeleteExpiredSessions(now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted := 0
+	for tokenHash, session := range r.sessions {
+		if session.Expired(now) {
+			r.recordSession(tokenHash)
+			delete(r.sessions, tokenHash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DeletePlayerAuth removes a player's credentials and all their sessions.
+func (r *InMemoryAuthRepository) DeletePlayerAuth(playerID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.credentials[playerID]; ok {
+		r.recordCredentials(playerID)
+		delete(r.credentials, playerID)
+	}
+	for tokenHash, session := range r.sessions {
+		if session.PlayerID == playerID {
+			r.recordSession(tokenHash)
+			delete(r.sessions, tokenHash)
+		}
+	}
+	return nil
+}
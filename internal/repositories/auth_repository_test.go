@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+func authRepositories(t *testing.T, test func(t *testing.T, repo AuthRepository)) {
+	forEachBackend(t,
+		func() AuthRepository { return NewInMemoryAuthRepository() },
+		func(db *sql.DB) AuthRepository { return NewSQLAuthRepository(db) },
+		test)
+}
+
+func TestAuthRepository_Credentials(t *testing.T) {
+	authRepositories(t, func(t *testing.T, repo AuthRepository) {
+		credentials := &models.Credentials{PlayerID: 1, Username: "alice", PasswordHash: "$2a$10$hash"}
+		if err := repo.CreateCredentials(credentials); err != nil {
+			t.Fatalf("Error creating credentials: %v", err)
+		}
+
+		byName, err := repo.GetCredentialsByUsername("alice")
+		if err != nil {
+			t.Fatalf("Error fetching credentials by username: %v", err)
+		}
+		byPlayer, err := repo.GetCredentialsByPlayerID(1)
+		if err != nil {
+			t.Fatalf("Error fetching credentials by player: %v", err)
+		}
+		for _, got := range []*models.Credentials{byName, byPlayer} {
+			if got.PlayerID != 1 || got.Username != "alice" || got.PasswordHash != credentials.PasswordHash ||
+				!got.CreatedAt.Equal(credentials.CreatedAt) {
+				t.Errorf("Expected %+v, got %+v", credentials, got)
+			}
+		}
+
+		// 用户名必须唯一
+		err = repo.CreateCredentials(&models.Credentials{PlayerID: 2, Username: "alice", PasswordHash: "x"})
+		if !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("Expected ErrUsernameTaken, got %v", err)
+		}
+		if _, err := repo.GetCredentialsByUsername("bob"); !errors.Is(err, ErrCredentialsNotFound) {
+			t.Errorf("Expected ErrCredentialsNotFound, got %v", err)
+		}
+	})
+}
+
+func TestAuthRepository_Sessions(t *testing.T) {
+	authRepositories(t, func(t *testing.T, repo AuthRepository) {
+		now := time.Date(2024, 7, 20, 15, 0, 0, 0, time.UTC)
+		sessions := []*models.Session{
+			{TokenHash: "live", PlayerID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{TokenHash: "expired", PlayerID: 1, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+		}
+		for _, session := range sessions {
+			if err := repo.CreateSession(session); err != nil {
+				t.Fatalf("Error creating session: %v", err)
+			}
+		}
+
+		session, err := repo.GetSession("live")
+		if err != nil {
+			t.Fatalf("Error fetching session: %v", err)
+		}
+		if session.PlayerID != 1 || !session.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("Expected %+v, got %+v", sessions[0], session)
+		}
+
+		deleted, err := repo.DeleteExpiredSessions(now)
+		if err != nil {
+			t.Fatalf("Error deleting expired sessions: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("Expected 1 expired session deleted, got %d", deleted)
+		}
+		if _, err := repo.GetSession("expired"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected expired session to be gone, got %v", err)
+		}
+
+		if err := repo.DeleteSession("live"); err != nil {
+			t.Fatalf("Error deleting session: %v", err)
+		}
+		if err := repo.DeleteSession("live"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound deleting twice, got %v", err)
+		}
+	})
+}
+
+func TestAuthRepository_DeletePlayerAuth(t *testing.T) {
+	authRepositories(t, func(t *testing.T, repo AuthRepository) {
+		now := time.Date(2024, 7, 20, 15, 0, 0, 0, time.UTC)
+		for _, credentials := range []*models.Credentials{
+			{PlayerID: 1, Username: "alice", PasswordHash: "x"},
+			{PlayerID: 2, Username: "bob", PasswordHash: "y"},
+		} {
+			if err := repo.CreateCredentials(credentials); err != nil {
+				t.Fatalf("Error creating credentials: %v", err)
+			}
+		}
+		for _, session := range []*models.Session{
+			{TokenHash: "a1", PlayerID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{TokenHash: "a2", PlayerID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{TokenHash: "b1", PlayerID: 2, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		} {
+			if err := repo.CreateSession(session); err != nil {
+				t.Fatalf("Error creating session: %v", err)
+			}
+		}
+
+		if err := repo.DeletePlayerAuth(1); err != nil {
+			t.Fatalf("Error deleting player auth: %v", err)
+		}
+		if _, err := repo.GetCredentialsByPlayerID(1); !errors.Is(err, ErrCredentialsNotFound) {
+			t.Errorf("Expected credentials to be gone, got %v", err)
+		}
+		for _, tokenHash := range []string{"a1", "a2"} {
+			if _, err := repo.GetSession(tokenHash); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Expected session %s to be gone, got %v", tokenHash, err)
+			}
+		}
+		// 其他玩家不受影响
+		if _, err := repo.GetCredentialsByPlayerID(2); err != nil {
+			t.Errorf("Expected other credentials to remain, got %v", err)
+		}
+		if _, err := repo.GetSession("b1"); err != nil {
+			t.Errorf("Expected other session to remain, got %v", err)
+		}
+
+		if err := repo.DeletePlayerAuth(1); err != nil {
+			t.Errorf("Expected no error deleting twice, got %v", err)
+		}
+	})
+}
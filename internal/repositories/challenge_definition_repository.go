@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"oxo_game/internal/models"
+)
+
+var (
+	ErrChallengeDefinitionNotFound = errors.New("challenge definition not found")
+)
+
+type ChallengeDefinitionRepository interface {
+	Create(definition *models.ChallengeDefinition) (int, error)
+	GetById(id int) (*models.ChallengeDefinition, error)
+	// List returns the definitions by ID.
+	List() []*models.ChallengeDefinition
+	Update(definition *models.ChallengeDefinition) error
+	Delete(id int) error
+}
+
+type InMemoryChallengeDefinitionRepository struct {
+	mu          sync.RWMutex
+	definitions map[int]*models.ChallengeDefinition
+	autoID      int
+}
+
+func NewInMemoryChallengeDefinitionRepository() *InMemoryChallengeDefinitionRepository {
+	return &InMemoryChallengeDefinitionRepository{
+		definitions: make(map[int]*models.ChallengeDefinition),
+		autoID:      0,
+	}
+}
+
+func (r *InMemoryChallengeDefinitionRepository) Create(definition *models.ChallengeDefinition) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.autoID++
+	definition.ID = r.autoID
+	stored := *definition
+	r.definitions[definition.ID] = &stored
+	return definition.ID, nil
+}
+
+func (r *InMemoryChallengeDefinitionRepository) GetById(id int) (*models.ChallengeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definition, ok := r.definitions[id]
+	if !ok {
+		return nil, ErrChallengeDefinitionNotFound
+	}
+	found := *definition
+	return &found, nil
+}
+
+func (r *InMemoryChallengeDefinitionRepository) List() []*models.ChallengeDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]*models.ChallengeDefinition, 0, len(r.definitions))
+	for _, definition := range r.definitions {
+		found := *definition
+		definitions = append(definitions, &found)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].ID < definitions[j].ID })
+	return definitions
+}
+
+func (r *InMemoryChallengeDefinitionRepository) Update(definition *models.ChallengeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.definitions[definition.ID]; !ok {
+		return ErrChallengeDefinitionNotFound
+	}
+	stored := *definition
+	r.definitions[definition.ID] = &stored
+	return nil
+}
+
+func (r *InMemoryChallengeDefinitionRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.definitions[id]; !ok {
+		return ErrChallengeDefinitionNotFound
+	}
+	delete(r.definitions, id)
+	return nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+func challengeDefinitionRepositories(t *testing.T, test func(t *testing.T, repo ChallengeDefinitionRepository)) {
+	forEachBackend(t,
+		func() ChallengeDefinitionRepository { return NewInMemoryChallengeDefinitionRepository() },
+		func(db *sql.DB) ChallengeDefinitionRepository { return NewSQLChallengeDefinitionRepository(db) },
+		test)
+}
+
+// withoutDefaults removes the definition the migrations create, so both
+// backends start empty.
+func withoutDefaults(t *testing.T, repo ChallengeDefinitionRepository) {
+	t.Helper()
+	for _, definition := range repo.List() {
+		if err := repo.Delete(definition.ID); err != nil {
+			t.Fatalf("Error deleting definition: %v", err)
+		}
+	}
+}
+
+func TestChallengeDefinitionRepository_CRUD(t *testing.T) {
+	challengeDefinitionRepositories(t, func(t *testing.T, repo ChallengeDefinitionRepository) {
+		withoutDefaults(t, repo)
+
+		from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		until := from.Add(30 * 24 * time.Hour)
+		definition := &models.ChallengeDefinition{
+			Name:            "High Roller",
+			Fee:             models.MustParseMoney("100.00"),
+			WinProbability:  0.05,
+			DurationSeconds: 60,
+			CooldownSeconds: 300,
+			MaxPerDay:       3,
+			MinLevelOrder:   2,
+			ActiveFrom:      &from,
+			ActiveUntil:     &until,
+		}
+		id, err := repo.Create(definition)
+		if err != nil {
+			t.Fatalf("Error creating definition: %v", err)
+		}
+
+		got, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching definition: %v", err)
+		}
+		if !definitionsAreEqual(got, definition) {
+			t.Errorf("Expected %+v, got %+v", definition, got)
+		}
+
+		// Open the window and make it cheaper
+		definition.Fee = models.MustParseMoney("75.50")
+		definition.ActiveFrom, definition.ActiveUntil = nil, nil
+		if err := repo.Update(definition); err != nil {
+			t.Fatalf("Error updating definition: %v", err)
+		}
+		listed := repo.List()
+		if len(listed) != 1 || !definitionsAreEqual(listed[0], definition) {
+			t.Errorf("Expected [%+v], got %+v", definition, listed)
+		}
+
+		if err := repo.Delete(id); err != nil {
+			t.Fatalf("Error deleting definition: %v", err)
+		}
+		if _, err := repo.GetById(id); !errors.Is(err, ErrChallengeDefinitionNotFound) {
+			t.Errorf("Expected ErrChallengeDefinitionNotFound, got %v", err)
+		}
+		if err := repo.Update(definition); !errors.Is(err, ErrChallengeDefinitionNotFound) {
+			t.Errorf("Expected ErrChallengeDefinitionNotFound updating a deleted definition, got %v", err)
+		}
+	})
+}
+
+func definitionsAreEqual(a, b *models.ChallengeDefinition) bool {
+	sameTime := func(x, y *time.Time) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Equal(*y)
+	}
+	a2, b2 := *a, *b
+	a2.ActiveFrom, a2.ActiveUntil, b2.ActiveFrom, b2.ActiveUntil = nil, nil, nil, nil
+	return reflect.DeepEqual(a2, b2) && sameTime(a.ActiveFrom, b.ActiveFrom) && sameTime(a.ActiveUntil, b.ActiveUntil)
+}
@@ -20,6 +20,11 @@ type ChallengeRepository interface {
 }
 
 type InMemoryChallengeRepository struct {
+	*challengeStore
+	journal *undoJournal
+}
+
+type challengeStore struct {
 	mu         sync.RWMutex
 	challenges map[int]*models.Challenge
 	autoID     int
@@ -27,11 +32,17 @@ type InMemoryChallengeRepository struct {
 
 func NewInMemoryChallengeRepository() *InMemoryChallengeRepository {
 	return &InMemoryChallengeRepository{
-		challenges: make(map[int]*models.Challenge),
-		autoID:     0,
+		challengeStore: &challengeStore{
+			challenges: make(map[int]*models.Challenge),
+			autoID:     0,
+		},
 	}
 }
 
+func (r *InMemoryChallengeRepository) withJournal(j *undoJournal) ChallengeRepository {
+	return &InMemoryChallengeRepository{challengeStore: r.challengeStore, journal: j}
+}
+
 func (r *InMemoryChallengeRepository) Create(challenge *models.Challenge) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -40,6 +51,12 @@ func (r *InMemoryChallengeRepository) Create(challenge *models.Challenge) (int,
 	challenge.ID = r.autoID
 	challenge.CreatedAt = time.Now()
 	r.challenges[challenge.ID] = challenge
+	id := challenge.ID
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.challenges, id)
+	})
 	return challenge.ID, nil
 }
 
@@ -49,7 +66,7 @@ func (r *InMemoryChallengeRepository) GetById(id int) (*models.Challenge, error)
 
 	challenge, ok := r.challenges[id]
 	if !ok {
-		return nil, errors.New("challenge not found")
+		return nil, ErrChallengeNotFound
 	}
 	return challenge, nil
 }
@@ -73,7 +90,8 @@ func (r *InMemoryChallengeRepository) ListLatest(n int) []*models.Challenge {
 
 	var challenges []*models.Challenge
 	count := 0
-	for id := len(r.challenges); id > 0; id-- {
+	// IDs of rolled back challenges are not reused, so there may be gaps
+	for id := r.autoID; id > 0; id-- {
 		if challenge, ok := r.challenges[id]; ok {
 			challenges = append(challenges, challenge)
 			count++
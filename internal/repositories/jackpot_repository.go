@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+var (
+	ErrJackpotPoolNotFound = errors.New("jackpot pool not found")
+	ErrJackpotPoolExists   = errors.New("jackpot pool already exists")
+)
+
+// jackpotPoolID is the ID of the single jackpot pool.
+const jackpotPoolID = 1
+
+// JackpotRepository stores the jackpot pool and the payouts made from it.
+// The pool's balance is only ever changed relative to its stored value, so
+// several instances sharing a database never lose or repeat a change.
+type JackpotRepository interface {
+	GetPool() (*models.JackpotPool, error)
+	// CreatePool creates the pool holding balance, or returns an error if it
+	// already exists.
+	CreatePool(balance models.Money) error
+	// AddToPool adds amount to the pool's balance.
+	AddToPool(amount models.Money) error
+	// ResetPool sets the pool's balance to balance and returns the balance
+	// it replaced.
+	ResetPool(balance models.Money) (models.Money, error)
+	CreatePayout(payout *models.JackpotPayout) (int, error)
+	ListPayouts(n int) []*models.JackpotPayout
+}
+
+// InMemoryJackpotRepository is an example of a repository using in-memory storage.
+type InMemoryJackpotRepository struct {
+	*jackpotStore
+	journal *undoJournal
+}
+
+type jackpotStore struct {
+	mu      sync.RWMutex
+	pool    *models.JackpotPool
+	payouts map[int]*models.JackpotPayout
+	autoID  int
+}
+
+// NewInMemoryJackpotRepository creates a new InMemoryJackpotRepository.
+func NewInMemoryJackpotRepository() *InMemoryJackpotRepository {
+	return &InMemoryJackpotRepository{
+		jackpotStore: &jackpotStore{
+			payouts: make(map[int]*models.JackpotPayout),
+			autoID:  0,
+		},
+	}
+}
+
+func (r *InMemoryJackpotRepository) withJournal(j *undoJournal) JackpotRepository {
+	return &InMemoryJackpotRepository{jackpotStore: r.jackpotStore, journal: j}
+}
+
+// GetPool returns the jackpot pool, or ErrJackpotPoolNotFound before it has
+// first been saved.
+func (r *InMemoryJackpotRepository) GetPool() (*models.JackpotPool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.pool == nil {
+		return nil, ErrJackpotPoolNotFound
+	}
+	pool := *r.pool
+	return &pool, nil
+}
+
+// CreatePool creates the jackpot pool.
+func (r *InMemoryJackpotRepository) CreatePool(balance models.Money) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool != nil {
+		return ErrJackpotPoolExists
+	}
+	r.setPool(balance)
+	return nil
+}
+
+// AddToPool adds amount to the pool's balance.
+func (r *InMemoryJackpotRepository) AddToPool(amount models.Money) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool == nil {
+		return ErrJackpotPoolNotFound
+	}
+	r.setPool(r.pool.Balance.Add(amount))
+	return nil
+}
+
+// ResetPool sets the pool's balance and returns the balance it replaced.
+func (r *InMemoryJackpotRepository) ResetPool(balance models.Money) (models.Money, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool == nil {
+		return models.Money{}, ErrJackpotPoolNotFound
+	}
+	replaced := r.pool.Balance
+	r.setPool(balance)
+	return replaced, nil
+}
+
+// setPool replaces the pool. The caller holds the write lock.
+func (r *InMemoryJackpotRepository) setPool(balance models.Money) {
+	previous := r.pool
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.pool = previous
+	})
+	r.pool = &models.JackpotPool{ID: jackpotPoolID, Balance: balance, UpdatedAt: time.Now().Unix()}
+}
+
+// CreatePayout records a payout and returns its ID.
+func (r *InMemoryJackpotRepository) CreatePayout(payout *models.JackpotPayout) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autoID++
+	payout.ID = r.autoID
+	payout.CreatedAt = time.Now().Unix()
+	r.payouts[payout.ID] = payout
+	id := payout.ID
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.payouts, id)
+	})
+	return payout.ID, nil
+}
+
+// ListPayouts returns the latest n payouts, newest first.
+func (r *InMemoryJackpotRepository) ListPayouts(n int) []*models.JackpotPayout {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	payouts := make([]*models.JackpotPayout, 0)
+	for id := r.autoID; id > 0 && len(payouts) < n; id-- {
+		if payout, ok := r.payouts[id]; ok {
+			payouts = append(payouts, payout)
+		}
+	}
+	return payouts
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"oxo_game/internal/models"
+)
+
+func jackpotRepositories(t *testing.T, test func(t *testing.T, repo JackpotRepository)) {
+	forEachBackend(t,
+		func() JackpotRepository { return NewInMemoryJackpotRepository() },
+		func(db *sql.DB) JackpotRepository { return NewSQLJackpotRepository(db) },
+		test)
+}
+
+func TestJackpotRepository_Pool(t *testing.T) {
+	jackpotRepositories(t, func(t *testing.T, repo JackpotRepository) {
+		// The pool does not exist until it is created
+		_, err := repo.GetPool()
+		if !errors.Is(err, ErrJackpotPoolNotFound) {
+			t.Fatalf("Expected ErrJackpotPoolNotFound, got %v", err)
+		}
+		if err := repo.AddToPool(models.Cents(1_00)); !errors.Is(err, ErrJackpotPoolNotFound) {
+			t.Errorf("Expected ErrJackpotPoolNotFound adding to a missing pool, got %v", err)
+		}
+
+		// Create the pool, only once
+		if err := repo.CreatePool(models.Cents(100_00)); err != nil {
+			t.Fatalf("Error creating pool: %v", err)
+		}
+		if err := repo.CreatePool(models.Cents(100_00)); err == nil {
+			t.Errorf("Expected an error creating the pool twice")
+		}
+
+		// Add to the pool
+		for _, amount := range []models.Money{models.Cents(10_00), models.Cents(50)} {
+			if err := repo.AddToPool(amount); err != nil {
+				t.Fatalf("Error adding to pool: %v", err)
+			}
+		}
+
+		pool, err := repo.GetPool()
+		if err != nil {
+			t.Fatalf("Error fetching pool: %v", err)
+		}
+		if pool.Balance != models.Cents(110_50) {
+			t.Errorf("Expected pool balance 110.50, got %s", pool.Balance)
+		}
+
+		// Reset the pool, getting back what it held
+		replaced, err := repo.ResetPool(models.Cents(100_00))
+		if err != nil {
+			t.Fatalf("Error resetting pool: %v", err)
+		}
+		if replaced != models.Cents(110_50) {
+			t.Errorf("Expected the reset to replace 110.50, got %s", replaced)
+		}
+		if pool, err := repo.GetPool(); err != nil || pool.Balance != models.Cents(100_00) {
+			t.Errorf("Expected pool balance 100.00 after the reset, got %v, %v", pool, err)
+		}
+	})
+}
+
+func TestJackpotRepository_Payouts(t *testing.T) {
+	jackpotRepositories(t, func(t *testing.T, repo JackpotRepository) {
+		// Record a few payouts
+		for i := 1; i <= 3; i++ {
+			payout := &models.JackpotPayout{PlayerID: i, ChallengeID: 10 + i, Amount: models.Cents(int64(i) * 100_00)}
+			if _, err := repo.CreatePayout(payout); err != nil {
+				t.Fatalf("Error creating payout: %v", err)
+			}
+		}
+
+		// The latest two come back newest first
+		payouts := repo.ListPayouts(2)
+		if len(payouts) != 2 {
+			t.Fatalf("Expected 2 payouts, got %d", len(payouts))
+		}
+		if payouts[0].PlayerID != 3 || payouts[1].PlayerID != 2 {
+			t.Errorf("Expected payouts of players 3 and 2, got %d and %d", payouts[0].PlayerID, payouts[1].PlayerID)
+		}
+		if payouts[0].ChallengeID != 13 || payouts[0].Amount != models.Cents(300_00) {
+			t.Errorf("Unexpected payout %+v", payouts[0])
+		}
+	})
+}
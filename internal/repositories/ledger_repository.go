@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// LedgerRepository is an append-only store of ledger transactions. Post
+// writes a transaction and its entries with several statements, so call it
+// from within a unit of work.
+type LedgerRepository interface {
+	Post(transaction *models.LedgerTransaction) (int, error)
+	ListByAccount(account string) ([]*models.LedgerTransaction, error)
+	AccountBalance(account string) (models.Money, error)
+}
+
+// InMemoryLedgerRepository is an example of a repository using in-memory storage.
+type InMemoryLedgerRepository struct {
+	*ledgerStore
+	journal *undoJournal
+}
+
+type ledgerStore struct {
+	mu            sync.RWMutex
+	transactions  []*models.LedgerTransaction
+	transactionID int
+	entryID       int
+}
+
+// NewInMemoryLedgerRepository creates a new InMemoryLedgerRepository.
+func NewInMemoryLedgerRepository() *InMemoryLedgerRepository {
+	return &InMemoryLedgerRepository{ledgerStore: &ledgerStore{}}
+}
+
+func (r *InMemoryLedgerRepository) withJournal(j *undoJournal) LedgerRepository {
+	return &InMemoryLedgerRepository{ledgerStore: r.ledgerStore, journal: j}
+}
+
+// Post appends a transaction and returns its ID.
+func (r *InMemoryLedgerRepository) Post(transaction *models.LedgerTransaction) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transactionID++
+	transaction.ID = r.transactionID
+	transaction.CreatedAt = time.Now().Unix()
+	for i := range transaction.Entries {
+		r.entryID++
+		transaction.Entries[i].ID = r.entryID
+		transaction.Entries[i].TransactionID = transaction.ID
+	}
+	r.transactions = append(r.transactions, transaction)
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.transactions = slices.DeleteFunc(r.transactions, func(t *models.LedgerTransaction) bool {
+			return t == transaction
+		})
+	})
+	return transaction.ID, nil
+}
+
+// ListByAccount returns the transactions that touched account, oldest first.
+func (r *InMemoryLedgerRepository) ListByAccount(account string) ([]*models.LedgerTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := make([]*models.LedgerTransaction, 0)
+	for _, transaction := range r.transactions {
+		for _, entry := range transaction.Entries {
+			if entry.Account == account {
+				transactions = append(transactions, transaction)
+				break
+			}
+		}
+	}
+	return transactions, nil
+}
+
+// AccountBalance returns the sum of every entry posted to account.
+func (r *InMemoryLedgerRepository) AccountBalance(account string) (models.Money, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var balance models.Money
+	for _, transaction := range r.transactions {
+		balance = balance.Add(transaction.AmountFor(account))
+	}
+	return balance, nil
+}
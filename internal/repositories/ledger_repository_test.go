@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+
+	"oxo_game/internal/models"
+)
+
+func ledgerRepositories(t *testing.T, test func(t *testing.T, repo LedgerRepository)) {
+	forEachBackend(t,
+		func() LedgerRepository { return NewInMemoryLedgerRepository() },
+		func(db *sql.DB) LedgerRepository { return NewSQLLedgerRepository(db) },
+		test)
+}
+
+func TestLedgerRepository_PostAndList(t *testing.T) {
+	ledgerRepositories(t, func(t *testing.T, repo LedgerRepository) {
+		player := models.PlayerAccount(1)
+
+		// Top up the player, then charge a challenge fee
+		transactions := []*models.LedgerTransaction{
+			{
+				Kind:      models.LedgerKindTopUp,
+				Reference: "payment:1",
+				Entries: []models.LedgerEntry{
+					{Account: player, Amount: models.Cents(50_00)},
+					{Account: models.ExternalAccount("fake"), Amount: models.Cents(-50_00)},
+				},
+			},
+			{
+				Kind:      models.LedgerKindChallengeFee,
+				Reference: "challenge:1",
+				Entries: []models.LedgerEntry{
+					{Account: player, Amount: models.Cents(-20_00)},
+					{Account: models.AccountJackpot, Amount: models.Cents(10_00)},
+					{Account: models.AccountRevenue, Amount: models.Cents(10_00)},
+				},
+			},
+		}
+		for _, transaction := range transactions {
+			if _, err := repo.Post(transaction); err != nil {
+				t.Fatalf("Error posting transaction: %v", err)
+			}
+		}
+
+		// Both transactions show up in the player's history, oldest first
+		history, err := repo.ListByAccount(player)
+		if err != nil {
+			t.Fatalf("Error listing transactions: %v", err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("Expected 2 transactions for %s, got %d", player, len(history))
+		}
+		if history[0].Kind != models.LedgerKindTopUp || history[1].Kind != models.LedgerKindChallengeFee {
+			t.Errorf("Unexpected history order: %s, %s", history[0].Kind, history[1].Kind)
+		}
+		if len(history[1].Entries) != 3 {
+			t.Errorf("Expected 3 entries in the challenge fee, got %d", len(history[1].Entries))
+		}
+		if amount := history[1].AmountFor(player); amount != models.Cents(-20_00) {
+			t.Errorf("Expected the fee to move -20.00, got %s", amount)
+		}
+
+		// Only the fee touched the jackpot
+		jackpot, err := repo.ListByAccount(models.AccountJackpot)
+		if err != nil {
+			t.Fatalf("Error listing transactions: %v", err)
+		}
+		if len(jackpot) != 1 {
+			t.Errorf("Expected 1 jackpot transaction, got %d", len(jackpot))
+		}
+
+		// The balance is the sum of the player's entries
+		balance, err := repo.AccountBalance(player)
+		if err != nil {
+			t.Fatalf("Error fetching balance: %v", err)
+		}
+		if balance != models.Cents(30_00) {
+			t.Errorf("Expected balance 30.00, got %s", balance)
+		}
+
+		// Unused accounts have a zero balance
+		balance, err = repo.AccountBalance(models.PlayerAccount(2))
+		if err != nil {
+			t.Fatalf("Error fetching balance: %v", err)
+		}
+		if !balance.IsZero() {
+			t.Errorf("Expected balance 0.00, got %s", balance)
+		}
+	})
+}
@@ -2,18 +2,31 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"oxo_game/internal/models"
 )
 
+var (
+	ErrLevelNotFound = errors.New("level not found")
+)
+
 type LevelRepository interface {
 	Create(level *models.Level) (int, error)
 	GetById(id int) (*models.Level, error)
+	// List returns the levels sorted by Order.
 	List() []*models.Level
+	Update(level *models.Level) error
+	Delete(id int) error
 }
 
 type InMemoryLevelRepository struct {
+	*levelStore
+	journal *undoJournal
+}
+
+type levelStore struct {
 	mu     sync.RWMutex
 	levels map[int]*models.Level
 	autoID int
@@ -21,17 +34,39 @@ type InMemoryLevelRepository struct {
 
 func NewInMemoryLevelRepository() *InMemoryLevelRepository {
 	return &InMemoryLevelRepository{
-		levels: make(map[int]*models.Level),
-		autoID: 0,
+		levelStore: &levelStore{
+			levels: make(map[int]*models.Level),
+			autoID: 0,
+		},
 	}
 }
 
+func (r *InMemoryLevelRepository) withJournal(j *undoJournal) LevelRepository {
+	return &InMemoryLevelRepository{levelStore: r.levelStore, journal: j}
+}
+
+// recordLevel records how to put the level back as it is now, or remove it
+// if it does not exist yet. The caller holds the write lock.
+func (r *InMemoryLevelRepository) recordLevel(id int) {
+	level, existed := r.levels[id]
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.levels[id] = level
+		} else {
+			delete(r.levels, id)
+		}
+	})
+}
+
 func (r *InMemoryLevelRepository) Create(level *models.Level) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	r.autoID++
 	level.ID = r.autoID
+	r.recordLevel(level.ID)
 	r.levels[level.ID] = level
 	return level.ID, nil
 }
@@ -42,7 +77,7 @@ func (r *InMemoryLevelRepository) GetById(id int) (*models.Level, error) {
 
 	level, ok := r.levels[id]
 	if !ok {
-		return nil, errors.New("level not found")
+		return nil, ErrLevelNotFound
 	}
 	return level, nil
 }
@@ -55,5 +90,36 @@ func (r *InMemoryLevelRepository) List() []*models.Level {
 	for _, level := range r.levels {
 		levels = append(levels, level)
 	}
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].Order != levels[j].Order {
+			return levels[i].Order < levels[j].Order
+		}
+		return levels[i].ID < levels[j].ID
+	})
 	return levels
 }
+
+func (r *InMemoryLevelRepository) Update(level *models.Level) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.levels[level.ID]; !ok {
+		return ErrLevelNotFound
+	}
+	updated := *level
+	r.recordLevel(level.ID)
+	r.levels[level.ID] = &updated
+	return nil
+}
+
+func (r *InMemoryLevelRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.levels[id]; !ok {
+		return ErrLevelNotFound
+	}
+	r.recordLevel(id)
+	delete(r.levels, id)
+	return nil
+}
@@ -1,82 +1,194 @@
 package repositories
 
 import (
+	"database/sql"
+	"errors"
 	"reflect"
 	"testing"
 
 	"oxo_game/internal/models"
 )
 
-func TestInMemoryLevelRepository_CreateAndGetById(t *testing.T) {
-	repo := NewInMemoryLevelRepository()
-
-	// 创建一个等级
-	level := &models.Level{
-		Name: "Beginner",
-	}
-
-	id, err := repo.Create(level)
-	if err != nil {
-		t.Fatalf("Error creating level: %v", err)
-	}
-
-	// 通过ID获取等级
-	createdLevel, err := repo.GetById(id)
-	if err != nil {
-		t.Fatalf("Error fetching level by ID: %v", err)
-	}
-
-	// 检查创建的等级和获取的等级是否一致
-	if !reflect.DeepEqual(createdLevel, level) {
-		t.Errorf("Created level does not match expected. Expected %+v, got %+v", level, createdLevel)
-	}
+func levelRepositories(t *testing.T, test func(t *testing.T, repo LevelRepository)) {
+	forEachBackend(t,
+		func() LevelRepository { return NewInMemoryLevelRepository() },
+		func(db *sql.DB) LevelRepository { return NewSQLLevelRepository(db) },
+		test)
 }
 
-func TestInMemoryLevelRepository_GetById_NotFound(t *testing.T) {
-	repo := NewInMemoryLevelRepository()
+func TestLevelRepository_CreateAndGetById(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		// 创建一个等级
+		level := &models.Level{
+			Name: "Beginner",
+		}
+
+		id, err := repo.Create(level)
+		if err != nil {
+			t.Fatalf("Error creating level: %v", err)
+		}
+
+		// 通过ID获取等级
+		createdLevel, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching level by ID: %v", err)
+		}
+
+		// 检查创建的等级和获取的等级是否一致
+		if !reflect.DeepEqual(createdLevel, level) {
+			t.Errorf("Created level does not match expected. Expected %+v, got %+v", level, createdLevel)
+		}
+	})
+}
+
+func TestLevelRepository_GetById_NotFound(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		_, err := repo.GetById(999) // 999 不存在的ID
+		if err == nil {
+			t.Error("Expected error for level not found, but got nil")
+		}
+	})
+}
+
+func TestLevelRepository_List(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		// 创建几个等级
+		levels := []*models.Level{
+			{Name: "Beginner"},
+			{Name: "Intermediate"},
+			{Name: "Advanced"},
+		}
+
+		for _, level := range levels {
+			_, err := repo.Create(level)
+			if err != nil {
+				t.Fatalf("Error creating level: %v", err)
+			}
+		}
+
+		// 获取所有等级列表
+		listedLevels := repo.List()
 
-	_, err := repo.GetById(999) // 999 不存在的ID
-	if err == nil {
-		t.Error("Expected error for level not found, but got nil")
-	}
+		// 检查结果数量是否符合预期
+		if len(listedLevels) != len(levels) {
+			t.Errorf("Expected %d levels, but got %d", len(levels), len(listedLevels))
+		}
+
+		// 检查每个等级是否都在返回的列表中
+		for _, expectedLevel := range levels {
+			found := false
+			for _, listedLevel := range listedLevels {
+				if expectedLevel.Name == listedLevel.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Expected level %s not found in listed levels", expectedLevel.Name)
+			}
+		}
+	})
 }
 
-func TestInMemoryLevelRepository_List(t *testing.T) {
-	repo := NewInMemoryLevelRepository()
+func TestLevelRepository_ListSortedByOrder(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		// 创建顺序与等级顺序不同
+		for _, level := range []*models.Level{
+			{Name: "Gold", Order: 3, MinXP: 5000},
+			{Name: "Bronze", Order: 1, MinXP: 0},
+			{Name: "Silver", Order: 2, MinXP: 1000},
+		} {
+			if _, err := repo.Create(level); err != nil {
+				t.Fatalf("Error creating level: %v", err)
+			}
+		}
 
-	// 创建几个等级
-	levels := []*models.Level{
-		{Name: "Beginner"},
-		{Name: "Intermediate"},
-		{Name: "Advanced"},
-	}
+		var names []string
+		for _, level := range repo.List() {
+			names = append(names, level.Name)
+		}
+		if want := []string{"Bronze", "Silver", "Gold"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("Expected levels %v, got %v", want, names)
+		}
+	})
+}
 
-	for _, level := range levels {
-		_, err := repo.Create(level)
+func TestLevelRepository_UpdateAndDelete(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		level := &models.Level{Name: "Beginner", Order: 1}
+		id, err := repo.Create(level)
 		if err != nil {
 			t.Fatalf("Error creating level: %v", err)
 		}
-	}
 
-	// 获取所有等级列表
-	listedLevels := repo.List()
+		// 更新等级
+		updated := &models.Level{ID: id, Name: "Novice", Order: 2, MinXP: 100}
+		if err := repo.Update(updated); err != nil {
+			t.Fatalf("Error updating level: %v", err)
+		}
+		got, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching level by ID: %v", err)
+		}
+		if !reflect.DeepEqual(got, updated) {
+			t.Errorf("Expected %+v, got %+v", updated, got)
+		}
 
-	// 检查结果数量是否符合预期
-	if len(listedLevels) != len(levels) {
-		t.Errorf("Expected %d levels, but got %d", len(levels), len(listedLevels))
-	}
+		// 删除等级
+		if err := repo.Delete(id); err != nil {
+			t.Fatalf("Error deleting level: %v", err)
+		}
+		if _, err := repo.GetById(id); !errors.Is(err, ErrLevelNotFound) {
+			t.Errorf("Expected ErrLevelNotFound after delete, got %v", err)
+		}
 
-	// 检查每个等级是否都在返回的列表中
-	for _, expectedLevel := range levels {
-		found := false
-		for _, listedLevel := range listedLevels {
-			if expectedLevel.Name == listedLevel.Name {
-				found = true
-				break
-			}
+		// 不存在的等级
+		if err := repo.Update(&models.Level{ID: 999, Name: "Ghost"}); !errors.Is(err, ErrLevelNotFound) {
+			t.Errorf("Expected ErrLevelNotFound updating a missing level, got %v", err)
+		}
+		if err := repo.Delete(999); !errors.Is(err, ErrLevelNotFound) {
+			t.Errorf("Expected ErrLevelNotFound deleting a missing level, got %v", err)
+		}
+	})
+}
+
+func TestLevelRepository_Perks(t *testing.T) {
+	levelRepositories(t, func(t *testing.T, repo LevelRepository) {
+		fee := models.MustParseMoney("50.00")
+		level := &models.Level{Name: "Advanced", Order: 3, MinXP: 2000, Perks: models.LevelPerks{
+			RoomIDs:                  []int{2, 5},
+			MaxWeeklyReservations:    3,
+			ChallengeFee:             &fee,
+			ChallengeCooldownSeconds: 30,
+		}}
+		id, err := repo.Create(level)
+		if err != nil {
+			t.Fatalf("Error creating level: %v", err)
+		}
+		if _, err := repo.Create(&models.Level{Name: "Beginner", Order: 1}); err != nil {
+			t.Fatalf("Error creating level: %v", err)
+		}
+
+		got, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching level by ID: %v", err)
+		}
+		if !reflect.DeepEqual(got, level) {
+			t.Errorf("Expected %+v, got %+v", level, got)
+		}
+		listed := repo.List()
+		if len(listed) != 2 || !reflect.DeepEqual(listed[1].Perks, level.Perks) || listed[0].Perks.RoomIDs != nil {
+			t.Errorf("Expected Advanced's perks listed, got %+v", listed)
+		}
+
+		// 清除特权
+		updated := &models.Level{ID: id, Name: "Advanced", Order: 3, MinXP: 2000, Perks: models.LevelPerks{RoomIDs: []int{7}}}
+		if err := repo.Update(updated); err != nil {
+			t.Fatalf("Error updating level: %v", err)
 		}
-		if !found {
-			t.Errorf("Expected level %s not found in listed levels", expectedLevel.Name)
+		got, _ = repo.GetById(id)
+		if !reflect.DeepEqual(got, updated) {
+			t.Errorf("Expected %+v, got %+v", updated, got)
 		}
-	}
+	})
 }
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"oxo_game/internal/models"
@@ -16,10 +17,77 @@ type LogRepository interface {
 	GetAllLogs() ([]models.Log, error)
 	GetLogByID(id int) (*models.Log, error)
 	CreateLog(log models.Log) (int, error)
+	// CreateLogs adds several logs at once and returns their IDs, in order.
+	// Either all of them are added or none.
+	CreateLogs(logs []models.Log) ([]int, error)
 	GetLogsByPlayerID(playerID int) ([]models.Log, error)
-	GetLogsByAction(action string) ([]models.Log, error)
+	GetLogsByAction(action models.LogAction) ([]models.Log, error)
 	GetLogsByTimeRange(startTime, endTime int64) ([]models.Log, error)
+	// Query returns the logs matching q in (timestamp, id) order.
+	Query(q LogQuery) ([]models.Log, error)
 	DeleteLog(id int) error
+	// DeleteLogs deletes the logs with the given IDs and returns how many
+	// there were. IDs without a log are skipped.
+	DeleteLogs(ids []int) (int, error)
+}
+
+// LogQuery selects logs. Zero fields do not filter.
+type LogQuery struct {
+	PlayerID int
+	Action   models.LogAction
+	// StartTime and EndTime bound Timestamp, inclusive.
+	StartTime int64
+	EndTime   int64
+	// Details selects logs whose details have all of these fields, compared
+	// as models.LogDetailText.
+	Details map[string]string
+	// After, if set, continues a previous query: only logs that come after
+	// this position in the query's order are returned.
+	After *LogPosition
+	// Descending returns the newest logs first.
+	Descending bool
+	// Limit caps the number of logs returned.
+	Limit int
+}
+
+// LogPosition is the place of a log in (timestamp, id) order.
+type LogPosition struct {
+	Timestamp int64
+	ID        int
+}
+
+// Before reports whether p sorts before o.
+func (p LogPosition) Before(o LogPosition) bool {
+	return p.Timestamp < o.Timestamp || p.Timestamp == o.Timestamp && p.ID < o.ID
+}
+
+// PositionOf returns the position of log.
+func PositionOf(log models.Log) LogPosition {
+	return LogPosition{Timestamp: log.Timestamp, ID: log.ID}
+}
+
+// Matches reports whether log passes the filters of q, ignoring Limit.
+func (q LogQuery) Matches(log models.Log) bool {
+	switch {
+	case q.PlayerID != 0 && log.PlayerID != q.PlayerID,
+		q.Action != "" && log.Action != q.Action,
+		q.StartTime != 0 && log.Timestamp < q.StartTime,
+		q.EndTime != 0 && log.Timestamp > q.EndTime:
+		return false
+	}
+	for name, want := range q.Details {
+		value, ok := log.Details[name]
+		if !ok || models.LogDetailText(value) != want {
+			return false
+		}
+	}
+	if q.After != nil {
+		if q.Descending {
+			return PositionOf(log).Before(*q.After)
+		}
+		return q.After.Before(PositionOf(log))
+	}
+	return true
 }
 
 // InMemoryLogRepository is an example of a repository using in-memory storage.
@@ -65,10 +133,26 @@ func (r *InMemoryLogRepository) CreateLog(log models.Log) (int, error) {
 	defer r.mu.Unlock()
 	r.autoID++
 	log.ID = r.autoID
+	log.Details = cloneDetails(log.Details)
 	r.logs[log.ID] = log
 	return log.ID, nil
 }
 
+// CreateLogs adds several logs at once and returns their IDs.
+func (r *InMemoryLogRepository) CreateLogs(logs []models.Log) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int, len(logs))
+	for i, log := range logs {
+		r.autoID++
+		log.ID = r.autoID
+		log.Details = cloneDetails(log.Details)
+		r.logs[log.ID] = log
+		ids[i] = log.ID
+	}
+	return ids, nil
+}
+
 // GetLogsByPlayerID returns logs for a specific player ID.
 func (r *InMemoryLogRepository) GetLogsByPlayerID(playerID int) ([]models.Log, error) {
 	r.mu.RLock()
@@ -83,7 +167,7 @@ func (r *InMemoryLogRepository) GetLogsByPlayerID(playerID int) ([]models.Log, e
 }
 
 // GetLogsByAction returns logs for a specific action.
-func (r *InMemoryLogRepository) GetLogsByAction(action string) ([]models.Log, error) {
+func (r *InMemoryLogRepository) GetLogsByAction(action models.LogAction) ([]models.Log, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var logs []models.Log
@@ -108,6 +192,29 @@ func (r *InMemoryLogRepository) GetLogsByTimeRange(startTime, endTime int64) ([]
 	return logs, nil
 }
 
+// Query returns the logs matching q in (timestamp, id) order.
+func (r *InMemoryLogRepository) Query(q LogQuery) ([]models.Log, error) {
+	r.mu.RLock()
+	var logs []models.Log
+	for _, log := range r.logs {
+		if q.Matches(log) {
+			logs = append(logs, log)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(logs, func(i, j int) bool {
+		if q.Descending {
+			return PositionOf(logs[j]).Before(PositionOf(logs[i]))
+		}
+		return PositionOf(logs[i]).Before(PositionOf(logs[j]))
+	})
+	if q.Limit > 0 && len(logs) > q.Limit {
+		logs = logs[:q.Limit]
+	}
+	return logs, nil
+}
+
 // DeleteLog deletes the log with the given ID.
 func (r *InMemoryLogRepository) DeleteLog(id int) error {
 	r.mu.Lock()
@@ -118,3 +225,26 @@ func (r *InMemoryLogRepository) DeleteLog(id int) error {
 	delete(r.logs, id)
 	return nil
 }
+
+// DeleteLogs deletes the logs with the given IDs and returns how many there
+// were.
+func (r *InMemoryLogRepository) DeleteLogs(ids []int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted := 0
+	for _, id := range ids {
+		if _, ok := r.logs[id]; ok {
+			delete(r.logs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cloneDetails(details models.LogDetails) models.LogDetails {
+	clone := make(models.LogDetails, len(details))
+	for name, value := range details {
+		clone[name] = value
+	}
+	return clone
+}
@@ -1,129 +1,261 @@
 package repositories
 
 import (
+	"database/sql"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
 	"oxo_game/internal/models"
 )
 
-func TestInMemoryLogRepository_CRUDOperations(t *testing.T) {
-	repo := NewInMemoryLogRepository()
+func logRepositories(t *testing.T, test func(t *testing.T, repo LogRepository)) {
+	forEachBackend(t,
+		func() LogRepository { return NewInMemoryLogRepository() },
+		func(db *sql.DB) LogRepository { return NewSQLLogRepository(db) },
+		test)
+}
 
-	// Create a log
-	log := models.Log{
-		PlayerID:  1,
-		Action:    "Login",
-		Timestamp: time.Now().Unix(),
-	}
+func TestLogRepository_CRUDOperations(t *testing.T) {
+	logRepositories(t, func(t *testing.T, repo LogRepository) {
+		// Create a log
+		log := models.Log{
+			PlayerID:  1,
+			Action:    "Login",
+			Details:   models.LogDetails{"note": "Player 1 logged in"},
+			Timestamp: time.Now().Unix(),
+		}
 
-	id, err := repo.CreateLog(log)
-	if err != nil {
-		t.Fatalf("Error creating log: %v", err)
-	}
+		id, err := repo.CreateLog(log)
+		if err != nil {
+			t.Fatalf("Error creating log: %v", err)
+		}
 
-	// Set the expected ID
-	log.ID = id
+		// Set the expected ID
+		log.ID = id
 
-	// Get log by ID
-	createdLog, err := repo.GetLogByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching log by ID: %v", err)
-	}
+		// Get log by ID
+		createdLog, err := repo.GetLogByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching log by ID: %v", err)
+		}
 
-	// Check if the created log matches the expected log
-	if !logsAreEqual(createdLog, &log) {
-		t.Errorf("Created log does not match expected. Expected %+v, got %+v", log, createdLog)
-	}
+		// Check if the created log matches the expected log
+		if !logsAreEqual(createdLog, &log) {
+			t.Errorf("Created log does not match expected. Expected %+v, got %+v", log, createdLog)
+		}
 
-	// Get all logs
-	allLogs, err := repo.GetAllLogs()
-	if err != nil {
-		t.Fatalf("Error fetching all logs: %v", err)
-	}
+		// Get all logs
+		allLogs, err := repo.GetAllLogs()
+		if err != nil {
+			t.Fatalf("Error fetching all logs: %v", err)
+		}
 
-	// Check if the created log exists in the list of all logs
-	found := false
-	for _, l := range allLogs {
-		if logsAreEqual(&l, &log) {
-			found = true
-			break
+		// Check if the created log exists in the list of all logs
+		found := false
+		for _, l := range allLogs {
+			if logsAreEqual(&l, &log) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Created log not found in GetAllLogs result")
 		}
-	}
-	if !found {
-		t.Errorf("Created log not found in GetAllLogs result")
-	}
 
-	// Get logs by player ID
-	playerLogs, err := repo.GetLogsByPlayerID(log.PlayerID)
-	if err != nil {
-		t.Fatalf("Error fetching logs by player ID: %v", err)
-	}
+		// Get logs by player ID
+		playerLogs, err := repo.GetLogsByPlayerID(log.PlayerID)
+		if err != nil {
+			t.Fatalf("Error fetching logs by player ID: %v", err)
+		}
 
-	// Check if the created log exists in the list of logs by player ID
-	found = false
-	for _, l := range playerLogs {
-		if logsAreEqual(&l, &log) {
-			found = true
-			break
+		// Check if the created log exists in the list of logs by player ID
+		found = false
+		for _, l := range playerLogs {
+			if logsAreEqual(&l, &log) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Created log not found in GetLogsByPlayerID result")
 		}
-	}
-	if !found {
-		t.Errorf("Created log not found in GetLogsByPlayerID result")
-	}
 
-	// Get logs by action
-	actionLogs, err := repo.GetLogsByAction(log.Action)
-	if err != nil {
-		t.Fatalf("Error fetching logs by action: %v", err)
-	}
+		// Get logs by action
+		actionLogs, err := repo.GetLogsByAction(log.Action)
+		if err != nil {
+			t.Fatalf("Error fetching logs by action: %v", err)
+		}
 
-	// Check if the created log exists in the list of logs by action
-	found = false
-	for _, l := range actionLogs {
-		if logsAreEqual(&l, &log) {
-			found = true
-			break
+		// Check if the created log exists in the list of logs by action
+		found = false
+		for _, l := range actionLogs {
+			if logsAreEqual(&l, &log) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Created log not found in GetLogsByAction result")
 		}
-	}
-	if !found {
-		t.Errorf("Created log not found in GetLogsByAction result")
-	}
 
-	// Get logs by time range (current timestamp - 1 minute to current timestamp + 1 minute)
-	startTime := log.Timestamp - 60
-	endTime := log.Timestamp + 60
-	timeRangeLogs, err := repo.GetLogsByTimeRange(startTime, endTime)
-	if err != nil {
-		t.Fatalf("Error fetching logs by time range: %v", err)
-	}
+		// Get logs by time range (current timestamp - 1 minute to current timestamp + 1 minute)
+		startTime := log.Timestamp - 60
+		endTime := log.Timestamp + 60
+		timeRangeLogs, err := repo.GetLogsByTimeRange(startTime, endTime)
+		if err != nil {
+			t.Fatalf("Error fetching logs by time range: %v", err)
+		}
 
-	// Check if the created log exists in the list of logs by time range
-	found = false
-	for _, l := range timeRangeLogs {
-		if logsAreEqual(&l, &log) {
-			found = true
-			break
+		// Check if the created log exists in the list of logs by time range
+		found = false
+		for _, l := range timeRangeLogs {
+			if logsAreEqual(&l, &log) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Created log not found in GetLogsByTimeRange result")
 		}
-	}
-	if !found {
-		t.Errorf("Created log not found in GetLogsByTimeRange result")
-	}
 
-	// Delete the log
-	err = repo.DeleteLog(id)
-	if err != nil {
-		t.Fatalf("Error deleting log: %v", err)
-	}
+		// Delete the log
+		err = repo.DeleteLog(id)
+		if err != nil {
+			t.Fatalf("Error deleting log: %v", err)
+		}
 
-	// Verify log deletion by trying to fetch it again
-	_, err = repo.GetLogByID(id)
-	if err == nil {
-		t.Errorf("Expected log to be deleted, but it still exists")
-	} else if !errors.Is(err, ErrLogNotFound) {
-		t.Errorf("Expected ErrLogNotFound, got %v", err)
-	}
+		// Verify log deletion by trying to fetch it again
+		_, err = repo.GetLogByID(id)
+		if err == nil {
+			t.Errorf("Expected log to be deleted, but it still exists")
+		} else if !errors.Is(err, ErrLogNotFound) {
+			t.Errorf("Expected ErrLogNotFound, got %v", err)
+		}
+	})
+}
+
+func TestLogRepository_Query(t *testing.T) {
+	logRepositories(t, func(t *testing.T, repo LogRepository) {
+		// 时间戳故意乱序写入，并有两条时间戳相同的日志
+		entries := []models.Log{
+			{PlayerID: 1, Action: models.LogActionLogin, Timestamp: 300, Details: models.LogDetails{"note": "vip"}},
+			{PlayerID: 2, Action: models.LogActionLogin, Timestamp: 100},
+			{PlayerID: 1, Action: models.LogActionLogout, Timestamp: 200},
+			{PlayerID: 1, Action: models.LogActionLogin, Timestamp: 200, Details: models.LogDetails{"note": "vip"}},
+			{PlayerID: 1, Action: models.LogActionEnterRoom, Timestamp: 400, Details: models.LogDetails{"room_id": float64(7), "note": "vip"}},
+		}
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			id, err := repo.CreateLog(entry)
+			if err != nil {
+				t.Fatalf("Error creating log: %v", err)
+			}
+			ids[i] = id
+		}
+
+		tests := []struct {
+			name  string
+			query LogQuery
+			want  []int
+		}{
+			{"all", LogQuery{}, []int{ids[1], ids[2], ids[3], ids[0], ids[4]}},
+			{"descending", LogQuery{Descending: true}, []int{ids[4], ids[0], ids[3], ids[2], ids[1]}},
+			{"player and action", LogQuery{PlayerID: 1, Action: models.LogActionLogin}, []int{ids[3], ids[0]}},
+			{"details", LogQuery{Details: map[string]string{"note": "vip"}}, []int{ids[3], ids[0], ids[4]}},
+			{"numeric details", LogQuery{Details: map[string]string{"note": "vip", "room_id": "7"}}, []int{ids[4]}},
+			{"time range", LogQuery{StartTime: 200, EndTime: 300}, []int{ids[2], ids[3], ids[0]}},
+			{"limit", LogQuery{Limit: 2}, []int{ids[1], ids[2]}},
+			{"after tie", LogQuery{After: &LogPosition{Timestamp: 200, ID: ids[2]}, Limit: 2}, []int{ids[3], ids[0]}},
+			{"descending after tie", LogQuery{After: &LogPosition{Timestamp: 200, ID: ids[3]}, Descending: true}, []int{ids[2], ids[1]}},
+		}
+		for _, tt := range tests {
+			logs, err := repo.Query(tt.query)
+			if err != nil {
+				t.Fatalf("%s: Error querying logs: %v", tt.name, err)
+			}
+			got := make([]int, len(logs))
+			for i, log := range logs {
+				got[i] = log.ID
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s: Expected logs %v, got %v", tt.name, tt.want, got)
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s: Expected logs %v, got %v", tt.name, tt.want, got)
+					break
+				}
+			}
+		}
+	})
+}
+
+func TestLogRepository_CreateLogs(t *testing.T) {
+	logRepositories(t, func(t *testing.T, repo LogRepository) {
+		logs := []models.Log{
+			{PlayerID: 1, Action: models.LogActionLogin, Timestamp: 100},
+			{PlayerID: 2, Action: models.LogActionEnterRoom, Timestamp: 101, Details: models.LogDetails{"room_id": float64(3)}},
+		}
+		ids, err := repo.CreateLogs(logs)
+		if err != nil {
+			t.Fatalf("Error creating logs: %v", err)
+		}
+		if len(ids) != len(logs) || ids[0] == ids[1] {
+			t.Fatalf("Expected %d distinct IDs, got %v", len(logs), ids)
+		}
+
+		for i, id := range ids {
+			logs[i].ID = id
+			if logs[i].Details == nil {
+				logs[i].Details = models.LogDetails{}
+			}
+			created, err := repo.GetLogByID(id)
+			if err != nil {
+				t.Fatalf("Error fetching log %d: %v", id, err)
+			}
+			if !logsAreEqual(created, &logs[i]) {
+				t.Errorf("Created log does not match expected. Expected %+v, got %+v", logs[i], created)
+			}
+		}
+	})
+}
+
+func TestLogRepository_DeleteLogs(t *testing.T) {
+	logRepositories(t, func(t *testing.T, repo LogRepository) {
+		var ids []int
+		for i := 0; i < 3; i++ {
+			id, err := repo.CreateLog(models.Log{
+				PlayerID:  1,
+				Action:    models.LogActionLogin,
+				Details:   models.LogDetails{"note": "old"},
+				Timestamp: int64(100 + i),
+			})
+			if err != nil {
+				t.Fatalf("Error creating log: %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		// 不存在的 ID 直接跳过
+		deleted, err := repo.DeleteLogs([]int{ids[0], ids[2], ids[2] + 100})
+		if err != nil {
+			t.Fatalf("Error deleting logs: %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("Expected 2 logs deleted, got %d", deleted)
+		}
+
+		logs, err := repo.Query(LogQuery{Details: map[string]string{"note": "old"}})
+		if err != nil {
+			t.Fatalf("Error querying logs: %v", err)
+		}
+		if len(logs) != 1 || logs[0].ID != ids[1] {
+			t.Errorf("Expected only log %d left, got %+v", ids[1], logs)
+		}
+	})
 }
 
 // logsAreEqual checks if two logs are equal considering their fields.
@@ -134,6 +266,7 @@ func logsAreEqual(l1, l2 *models.Log) bool {
 	return l1.ID == l2.ID &&
 		l1.PlayerID == l2.PlayerID &&
 		l1.Action == l2.Action &&
+		reflect.DeepEqual(l1.Details, l2.Details) &&
 		l1.Timestamp == l2.Timestamp &&
 		l1.CreatedAt == l2.CreatedAt &&
 		l1.UpdatedAt == l2.UpdatedAt
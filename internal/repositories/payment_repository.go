@@ -2,18 +2,29 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"oxo_game/internal/models"
 )
 
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+)
+
 type PaymentRepository interface {
 	Create(payment *models.Payment) (int, error)
 	GetById(id int) (*models.Payment, error)
+	ListByPlayer(playerID int) []*models.Payment
 }
 
 type InMemoryPaymentRepository struct {
+	*paymentStore
+	journal *undoJournal
+}
+
+type paymentStore struct {
 	mu       sync.RWMutex
 	payments map[int]*models.Payment
 	autoID   int
@@ -21,11 +32,17 @@ type InMemoryPaymentRepository struct {
 
 func NewInMemoryPaymentRepository() *InMemoryPaymentRepository {
 	return &InMemoryPaymentRepository{
-		payments: make(map[int]*models.Payment),
-		autoID:   0,
+		paymentStore: &paymentStore{
+			payments: make(map[int]*models.Payment),
+			autoID:   0,
+		},
 	}
 }
 
+func (r *InMemoryPaymentRepository) withJournal(j *undoJournal) PaymentRepository {
+	return &InMemoryPaymentRepository{paymentStore: r.paymentStore, journal: j}
+}
+
 func (r *InMemoryPaymentRepository) Create(payment *models.Payment) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -34,6 +51,12 @@ func (r *InMemoryPaymentRepository) Create(payment *models.Payment) (int, error)
 	payment.ID = r.autoID
 	payment.CreatedAt = time.Now().Unix()
 	r.payments[payment.ID] = payment
+	id := payment.ID
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.payments, id)
+	})
 	return payment.ID, nil
 }
 
@@ -43,7 +66,24 @@ func (r *InMemoryPaymentRepository) GetById(id int) (*models.Payment, error) {
 
 	payment, ok := r.payments[id]
 	if !ok {
-		return nil, errors.New("payment not found")
+		return nil, ErrPaymentNotFound
 	}
 	return payment, nil
 }
+
+// ListByPlayer returns the player's payments, oldest first.
+func (r *InMemoryPaymentRepository) ListByPlayer(playerID int) []*models.Payment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payments := make([]*models.Payment, 0)
+	for _, payment := range r.payments {
+		if payment.PlayerID == playerID {
+			payments = append(payments, payment)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].ID < payments[j].ID
+	})
+	return payments
+}
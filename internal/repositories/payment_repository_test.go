@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"oxo_game/internal/models"
+)
+
+func paymentRepositories(t *testing.T, test func(t *testing.T, repo PaymentRepository)) {
+	forEachBackend(t,
+		func() PaymentRepository { return NewInMemoryPaymentRepository() },
+		func(db *sql.DB) PaymentRepository { return NewSQLPaymentRepository(db) },
+		test)
+}
+
+func TestPaymentRepository_CreateAndGetById(t *testing.T) {
+	paymentRepositories(t, func(t *testing.T, repo PaymentRepository) {
+		// Create a payment
+		payment := &models.Payment{
+			PlayerID: 1,
+			Type:     models.PaymentTypeTopUp,
+			Method:   "fake",
+			Amount:   models.Cents(50_25),
+			Details:  "card ending 4242",
+		}
+
+		id, err := repo.Create(payment)
+		if err != nil {
+			t.Fatalf("Error creating payment: %v", err)
+		}
+
+		// Get payment by ID
+		createdPayment, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching payment by ID: %v", err)
+		}
+
+		// Check if the created payment matches the expected payment
+		if *createdPayment != *payment {
+			t.Errorf("Created payment does not match expected. Expected %+v, got %+v", payment, createdPayment)
+		}
+
+		// Unknown IDs are reported as not found
+		if _, err := repo.GetById(999); !errors.Is(err, ErrPaymentNotFound) {
+			t.Errorf("Expected ErrPaymentNotFound, got %v", err)
+		}
+	})
+}
+
+func TestPaymentRepository_ListByPlayer(t *testing.T) {
+	paymentRepositories(t, func(t *testing.T, repo PaymentRepository) {
+		// Create payments for two players
+		payments := []*models.Payment{
+			{PlayerID: 1, Type: models.PaymentTypeTopUp, Method: "fake", Amount: models.Cents(100_00)},
+			{PlayerID: 2, Type: models.PaymentTypeTopUp, Method: "fake", Amount: models.Cents(30_00)},
+			{PlayerID: 1, Type: models.PaymentTypeChallengeFee, Method: models.PaymentMethodWallet, Amount: models.Cents(-20_01)},
+		}
+		for _, payment := range payments {
+			if _, err := repo.Create(payment); err != nil {
+				t.Fatalf("Error creating payment: %v", err)
+			}
+		}
+
+		// Only player 1's payments come back, oldest first
+		playerPayments := repo.ListByPlayer(1)
+		if len(playerPayments) != 2 {
+			t.Fatalf("Expected 2 payments for player 1, got %d", len(playerPayments))
+		}
+		if playerPayments[0].Amount != models.Cents(100_00) || playerPayments[1].Amount != models.Cents(-20_01) {
+			t.Errorf("Unexpected payments %+v, %+v", playerPayments[0], playerPayments[1])
+		}
+	})
+}
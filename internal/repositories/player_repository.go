@@ -2,27 +2,50 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"oxo_game/internal/models"
 )
 
 var (
-	ErrPlayerNotFound = errors.New("player not found")
+	ErrPlayerNotFound      = errors.New("player not found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
 )
 
 // PlayerRepository is the interface that wraps the basic CRUD operations.
 type PlayerRepository interface {
 	GetAllPlayers() ([]models.Player, error)
 	GetPlayerByID(id int) (*models.Player, error)
+	// GetPlayersByLevel returns the players at the level, by ID.
+	GetPlayersByLevel(levelID int) ([]models.Player, error)
 	CreatePlayer(player models.Player) (int, error)
+	// UpdatePlayer updates the player's name, level and role. The balance
+	// and experience are left as stored; they only change through
+	// DeductBalance, CreditBalance and AddXP, so a concurrent change to them
+	// is never overwritten.
 	UpdatePlayer(id int, updatedPlayer models.Player) error
 	DeletePlayer(id int) error
-	DeductBalance(playerID int, amount float64) error
+	// MoveLevel moves every player at level from to level to, or to no
+	// level when to is nil.
+	MoveLevel(from int, to *models.Level) error
+	DeductBalance(playerID int, amount models.Money) error
+	CreditBalance(playerID int, amount models.Money) error
+	// AddXP adds xp, which may be negative, to the player's experience and
+	// returns the new total. Experience never drops below 0.
+	AddXP(playerID int, xp int64) (int64, error)
+	// RaiseXP raises the player's experience to at least xp and returns the
+	// new total. More experience is left as it is.
+	RaiseXP(playerID int, xp int64) (int64, error)
 }
 
 // InMemoryPlayerRepository is an example of a repository using in-memory storage.
 type InMemoryPlayerRepository struct {
+	*playerStore
+	journal *undoJournal
+}
+
+type playerStore struct {
 	mu      sync.RWMutex
 	players map[int]models.Player
 	autoID  int
@@ -31,11 +54,44 @@ type InMemoryPlayerRepository struct {
 // NewInMemoryPlayerRepository creates a new InMemoryPlayerRepository.
 func NewInMemoryPlayerRepository() *InMemoryPlayerRepository {
 	return &InMemoryPlayerRepository{
-		players: make(map[int]models.Player),
-		autoID:  0,
+		playerStore: &playerStore{
+			players: make(map[int]models.Player),
+			autoID:  0,
+		},
 	}
 }
 
+func (r *InMemoryPlayerRepository) withJournal(j *undoJournal) PlayerRepository {
+	return &InMemoryPlayerRepository{playerStore: r.playerStore, journal: j}
+}
+
+// recordPlayer records how to put the player back as it is now, or remove it
+// if it does not exist yet. The caller holds the write lock.
+func (r *InMemoryPlayerRepository) recordPlayer(id int) {
+	player, existed := r.players[id]
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.players[id] = player
+		} else {
+			delete(r.players, id)
+		}
+	})
+}
+
+// recordBalance records how to take amount back off the player's balance.
+func (r *InMemoryPlayerRepository) recordBalance(id int, amount models.Money) {
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if player, ok := r.players[id]; ok {
+			player.Balance = player.Balance.Sub(amount)
+			r.players[id] = player
+		}
+	})
+}
+
 // GetAllPlayers returns all players.
 func (r *InMemoryPlayerRepository) GetAllPlayers() ([]models.Player, error) {
 	r.mu.RLock()
@@ -58,12 +114,27 @@ func (r *InMemoryPlayerRepository) GetPlayerByID(id int) (*models.Player, error)
 	return &player, nil
 }
 
+// GetPlayersByLevel returns the players at the level.
+func (r *InMemoryPlayerRepository) GetPlayersByLevel(levelID int) ([]models.Player, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	players := make([]models.Player, 0)
+	for _, player := range r.players {
+		if player.Level != nil && player.Level.ID == levelID {
+			players = append(players, player)
+		}
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players, nil
+}
+
 // CreatePlayer adds a new player and returns the new player's ID.
 func (r *InMemoryPlayerRepository) CreatePlayer(player models.Player) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.autoID++
 	player.ID = r.autoID
+	r.recordPlayer(player.ID)
 	r.players[player.ID] = player
 	return player.ID, nil
 }
@@ -72,14 +143,32 @@ func (r *InMemoryPlayerRepository) CreatePlayer(player models.Player) (int, erro
 func (r *InMemoryPlayerRepository) UpdatePlayer(id int, updatedPlayer models.Player) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.players[id]; !ok {
+	current, ok := r.players[id]
+	if !ok {
 		return ErrPlayerNotFound
 	}
 	updatedPlayer.ID = id
+	updatedPlayer.Balance = current.Balance
+	updatedPlayer.XP = current.XP
+	r.recordPlayer(id)
 	r.players[id] = updatedPlayer
 	return nil
 }
 
+// MoveLevel moves every player at level from to level to.
+func (r *InMemoryPlayerRepository) MoveLevel(from int, to *models.Level) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, player := range r.players {
+		if player.Level != nil && player.Level.ID == from {
+			r.recordPlayer(id)
+			player.Level = to
+			r.players[id] = player
+		}
+	}
+	return nil
+}
+
 // DeletePlayer deletes the player with the given ID.
 func (r *InMemoryPlayerRepository) DeletePlayer(id int) error {
 	r.mu.Lock()
@@ -87,22 +176,85 @@ func (r *InMemoryPlayerRepository) DeletePlayer(id int) error {
 	if _, ok := r.players[id]; !ok {
 		return ErrPlayerNotFound
 	}
+	r.recordPlayer(id)
 	delete(r.players, id)
 	return nil
 }
-func (r *InMemoryPlayerRepository) DeductBalance(playerID int, amount float64) error {
+
+// DeductBalance subtracts amount from the player's balance.
+func (r *InMemoryPlayerRepository) DeductBalance(playerID int, amount models.Money) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	player, ok := r.players[playerID]
 	if !ok {
-		return errors.New("player not found")
+		return ErrPlayerNotFound
 	}
 
-	if player.Balance < amount {
-		return errors.New("insufficient balance")
+	if player.Balance.Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+
+	player.Balance = player.Balance.Sub(amount)
+	r.players[playerID] = player
+	r.recordBalance(playerID, amount.Neg())
+	return nil
+}
+
+// CreditBalance adds amount to the player's balance.
+func (r *InMemoryPlayerRepository) CreditBalance(playerID int, amount models.Money) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[playerID]
+	if !ok {
+		return ErrPlayerNotFound
 	}
 
-	player.Balance -= amount
+	player.Balance = player.Balance.Add(amount)
+	r.players[playerID] = player
+	r.recordBalance(playerID, amount)
 	return nil
 }
+
+// AddXP adds xp to the player's experience and returns the new total.
+func (r *InMemoryPlayerRepository) AddXP(playerID int, xp int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[playerID]
+	if !ok {
+		return 0, ErrPlayerNotFound
+	}
+
+	return r.addXP(player, max(player.XP+xp, 0)-player.XP), nil
+}
+
+// RaiseXP raises the player's experience to at least xp.
+func (r *InMemoryPlayerRepository) RaiseXP(playerID int, xp int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[playerID]
+	if !ok {
+		return 0, ErrPlayerNotFound
+	}
+	return r.addXP(player, max(xp-player.XP, 0)), nil
+}
+
+// addXP adds added to the player's experience and records how to take it
+// back off. The caller holds the write lock.
+func (r *InMemoryPlayerRepository) addXP(player models.Player, added int64) int64 {
+	player.XP += added
+	r.players[player.ID] = player
+	playerID := player.ID
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if player, ok := r.players[playerID]; ok {
+			player.XP = max(player.XP-added, 0)
+			r.players[playerID] = player
+		}
+	})
+	return player.XP
+}
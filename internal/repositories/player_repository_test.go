@@ -1,125 +1,340 @@
 package repositories
 
 import (
+	"database/sql"
 	"errors"
+	"reflect"
 	"testing"
 
 	"oxo_game/internal/models"
 )
 
-func TestInMemoryPlayerRepository_CRUDOperations(t *testing.T) {
-	repo := NewInMemoryPlayerRepository()
+// playerBackend pairs a PlayerRepository with the LevelRepository holding the
+// levels its players refer to.
+type playerBackend struct {
+	players PlayerRepository
+	levels  LevelRepository
+}
 
-	// Create a player
-	player := models.Player{
-		Name:    "Alice",
-		Level:   &models.Level{Name: "Beginner"},
-		Balance: 100.0,
-	}
+func playerRepositories(t *testing.T, test func(t *testing.T, repo PlayerRepository, levels LevelRepository)) {
+	forEachBackend(t,
+		func() playerBackend {
+			return playerBackend{NewInMemoryPlayerRepository(), NewInMemoryLevelRepository()}
+		},
+		func(db *sql.DB) playerBackend {
+			return playerBackend{NewSQLPlayerRepository(db), NewSQLLevelRepository(db)}
+		},
+		func(t *testing.T, b playerBackend) { test(t, b.players, b.levels) })
+}
 
-	id, err := repo.CreatePlayer(player)
-	if err != nil {
-		t.Fatalf("Error creating player: %v", err)
+// createLevel stores a level named name and returns it.
+func createLevel(t *testing.T, levels LevelRepository, name string) *models.Level {
+	t.Helper()
+	level := &models.Level{Name: name}
+	if _, err := levels.Create(level); err != nil {
+		t.Fatalf("Error creating level: %v", err)
 	}
+	return level
+}
 
-	// Set the expected ID
-	player.ID = id
+func TestPlayerRepository_CRUDOperations(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		// Create a player
+		player := models.Player{
+			Name:    "Alice",
+			Level:   createLevel(t, levels, "Beginner"),
+			Balance: models.Cents(100_00),
+			Role:    models.RolePlayer,
+			XP:      120,
+		}
 
-	// Get player by ID
-	createdPlayer, err := repo.GetPlayerByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching player by ID: %v", err)
-	}
+		id, err := repo.CreatePlayer(player)
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
 
-	// Check if the created player matches the expected player
-	if !playersAreEqual(&player, createdPlayer) {
-		t.Errorf("Created player does not match expected. Expected %+v, got %+v", player, *createdPlayer)
-	}
+		// Set the expected ID
+		player.ID = id
 
-	// Update player
-	updatedPlayer := *createdPlayer
-	updatedPlayer.Name = "Updated Alice"
-	updatedPlayer.Balance = 150.0
+		// Get player by ID
+		createdPlayer, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player by ID: %v", err)
+		}
 
-	err = repo.UpdatePlayer(id, updatedPlayer)
-	if err != nil {
-		t.Fatalf("Error updating player: %v", err)
-	}
+		// Check if the created player matches the expected player
+		if !playersAreEqual(&player, createdPlayer) {
+			t.Errorf("Created player does not match expected. Expected %+v, got %+v", player, *createdPlayer)
+		}
 
-	// Get updated player by ID
-	updatedPlayerResult, err := repo.GetPlayerByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching updated player by ID: %v", err)
-	}
+		// Update player
+		updatedPlayer := *createdPlayer
+		updatedPlayer.Name = "Updated Alice"
+		updatedPlayer.Balance = models.Cents(150_00)
+		updatedPlayer.Role = models.RoleOperator
 
-	// Check if the updated player matches the expected updated player
-	if !playersAreEqual(&updatedPlayer, updatedPlayerResult) {
-		t.Errorf("Updated player does not match expected. Expected %+v, got %+v", updatedPlayer, *updatedPlayerResult)
-	}
+		err = repo.UpdatePlayer(id, updatedPlayer)
+		if err != nil {
+			t.Fatalf("Error updating player: %v", err)
+		}
 
-	// Delete player
-	err = repo.DeletePlayer(id)
-	if err != nil {
-		t.Fatalf("Error deleting player: %v", err)
-	}
+		// Get updated player by ID
+		updatedPlayerResult, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching updated player by ID: %v", err)
+		}
 
-	// Verify player deletion by trying to fetch it again
-	_, err = repo.GetPlayerByID(id)
-	if err == nil {
-		t.Errorf("Expected player to be deleted, but it still exists")
-	} else if !errors.Is(err, ErrPlayerNotFound) {
-		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
-	}
+		// The balance only changes through DeductBalance and CreditBalance
+		updatedPlayer.Balance = createdPlayer.Balance
+		if !playersAreEqual(&updatedPlayer, updatedPlayerResult) {
+			t.Errorf("Updated player does not match expected. Expected %+v, got %+v", updatedPlayer, *updatedPlayerResult)
+		}
+
+		// Delete player
+		err = repo.DeletePlayer(id)
+		if err != nil {
+			t.Fatalf("Error deleting player: %v", err)
+		}
+
+		// Verify player deletion by trying to fetch it again
+		_, err = repo.GetPlayerByID(id)
+		if err == nil {
+			t.Errorf("Expected player to be deleted, but it still exists")
+		} else if !errors.Is(err, ErrPlayerNotFound) {
+			t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+		}
+	})
 }
 
-func TestInMemoryPlayerRepository_DeductBalance(t *testing.T) {
-	repo := NewInMemoryPlayerRepository()
+func TestPlayerRepository_DeductBalance(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		// Create a player with initial balance
+		player := models.Player{
+			Name:    "Bob",
+			Level:   createLevel(t, levels, "Intermediate"),
+			Balance: models.Cents(200_00),
+		}
 
-	// Create a player with initial balance
-	player := models.Player{
-		Name:    "Bob",
-		Level:   &models.Level{Name: "Intermediate"},
-		Balance: 200.0,
-	}
+		id, err := repo.CreatePlayer(player)
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
 
-	id, err := repo.CreatePlayer(player)
-	if err != nil {
-		t.Fatalf("Error creating player: %v", err)
-	}
+		// Deduct balance from player
+		deductAmount := models.Cents(50_00)
+		err = repo.DeductBalance(id, deductAmount)
+		if err != nil {
+			t.Fatalf("Error deducting balance: %v", err)
+		}
 
-	// Deduct balance from player
-	deductAmount := 50.0
-	err = repo.DeductBalance(id, deductAmount)
-	if err != nil {
-		t.Fatalf("Error deducting balance: %v", err)
-	}
+		// Get player after balance deduction
+		updatedPlayer, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player by ID after balance deduction: %v", err)
+		}
 
-	// Get player after balance deduction
-	updatedPlayer, err := repo.GetPlayerByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching player by ID after balance deduction: %v", err)
-	}
+		// Check if the player's balance is correctly deducted
+		expectedBalance := player.Balance.Sub(deductAmount)
+		if updatedPlayer.Balance != expectedBalance {
+			t.Errorf("Expected balance after deduction to be %s, but got %s", expectedBalance, updatedPlayer.Balance)
+		}
 
-	// Check if the player's balance is correctly deducted
-	expectedBalance := player.Balance - deductAmount
-	if updatedPlayer.Balance != expectedBalance {
-		t.Errorf("Expected balance after deduction to be %.2f, but got %.2f", expectedBalance, updatedPlayer.Balance)
-	}
+		// Attempt to deduct more than the available balance
+		insufficientAmount := updatedPlayer.Balance.Add(models.Cents(10_00))
+		err = repo.DeductBalance(id, insufficientAmount)
+		if err == nil {
+			t.Errorf("Expected error for insufficient balance, but got nil")
+		} else if err.Error() != "insufficient balance" {
+			t.Errorf("Expected 'insufficient balance' error, but got %v", err)
+		}
 
-	// Attempt to deduct more than the available balance
-	insufficientAmount := updatedPlayer.Balance + 10.0
-	err = repo.DeductBalance(id, insufficientAmount)
-	if err == nil {
-		t.Errorf("Expected error for insufficient balance, but got nil")
-	} else if err.Error() != "insufficient balance" {
-		t.Errorf("Expected 'insufficient balance' error, but got %v", err)
-	}
+		// Delete player
+		err = repo.DeletePlayer(id)
+		if err != nil {
+			t.Fatalf("Error deleting player: %v", err)
+		}
+	})
+}
 
-	// Delete player
-	err = repo.DeletePlayer(id)
-	if err != nil {
-		t.Fatalf("Error deleting player: %v", err)
-	}
+func TestPlayerRepository_BalanceIsExact(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		id, err := repo.CreatePlayer(models.Player{Name: "Carol", Balance: models.MustParseMoney("60.03")})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		// 0.1 和 20.01 都无法用 float64 精确表示，连续扣款后余额必须恰好为 0
+		fee := models.MustParseMoney("20.01")
+		for i := 0; i < 3; i++ {
+			if err := repo.DeductBalance(id, fee); err != nil {
+				t.Fatalf("Error deducting fee %d: %v", i+1, err)
+			}
+		}
+		for i := 0; i < 10; i++ {
+			if err := repo.CreditBalance(id, models.MustParseMoney("0.1")); err != nil {
+				t.Fatalf("Error crediting balance: %v", err)
+			}
+		}
+		if err := repo.DeductBalance(id, models.Cents(1_00)); err != nil {
+			t.Fatalf("Error deducting the whole balance: %v", err)
+		}
+
+		player, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if !player.Balance.IsZero() {
+			t.Errorf("Expected balance 0.00, got %s", player.Balance)
+		}
+	})
+}
+
+func TestPlayerRepository_AddXP(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		id, err := repo.CreatePlayer(models.Player{Name: "Dave", XP: 40})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		xp, err := repo.AddXP(id, 25)
+		if err != nil {
+			t.Fatalf("Error adding XP: %v", err)
+		}
+		if xp != 65 {
+			t.Errorf("Expected 65 XP, got %d", xp)
+		}
+
+		// 经验值扣减后不能小于 0
+		xp, err = repo.AddXP(id, -100)
+		if err != nil {
+			t.Fatalf("Error removing XP: %v", err)
+		}
+		player, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if xp != 0 || player.XP != 0 {
+			t.Errorf("Expected XP to stop at 0, got %d (stored %d)", xp, player.XP)
+		}
+
+		if _, err := repo.AddXP(id+100, 10); !errors.Is(err, ErrPlayerNotFound) {
+			t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+		}
+	})
+}
+
+func TestPlayerRepository_RaiseXP(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		id, err := repo.CreatePlayer(models.Player{Name: "Dave", XP: 40})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		xp, err := repo.RaiseXP(id, 100)
+		if err != nil {
+			t.Fatalf("Error raising XP: %v", err)
+		}
+		if xp != 100 {
+			t.Errorf("Expected 100 XP, got %d", xp)
+		}
+
+		// 已经更高的经验值保持不变
+		xp, err = repo.RaiseXP(id, 60)
+		if err != nil {
+			t.Fatalf("Error raising XP: %v", err)
+		}
+		player, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if xp != 100 || player.XP != 100 {
+			t.Errorf("Expected XP to stay at 100, got %d (stored %d)", xp, player.XP)
+		}
+
+		if _, err := repo.RaiseXP(id+100, 10); !errors.Is(err, ErrPlayerNotFound) {
+			t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+		}
+	})
+}
+
+func TestPlayerRepository_GetPlayersByLevelAndMoveLevel(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		beginner := createLevel(t, levels, "Beginner")
+		expert := createLevel(t, levels, "Expert")
+		aliceID, _ := repo.CreatePlayer(models.Player{Name: "Alice", Level: beginner})
+		bobID, _ := repo.CreatePlayer(models.Player{Name: "Bob", Level: beginner})
+		if _, err := repo.CreatePlayer(models.Player{Name: "Carol", Level: expert}); err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		atBeginner, err := repo.GetPlayersByLevel(beginner.ID)
+		if err != nil {
+			t.Fatalf("Error fetching players by level: %v", err)
+		}
+		if len(atBeginner) != 2 || atBeginner[0].ID != aliceID || atBeginner[1].ID != bobID {
+			t.Fatalf("Expected Alice and Bob at Beginner, got %+v", atBeginner)
+		}
+
+		// Move everyone at Beginner up to Expert
+		if err := repo.MoveLevel(beginner.ID, expert); err != nil {
+			t.Fatalf("Error moving players: %v", err)
+		}
+		if players, _ := repo.GetPlayersByLevel(beginner.ID); len(players) != 0 {
+			t.Errorf("Expected nobody left at Beginner, got %+v", players)
+		}
+		if players, _ := repo.GetPlayersByLevel(expert.ID); len(players) != 3 {
+			t.Errorf("Expected 3 players at Expert, got %+v", players)
+		}
+
+		// And then to no level at all
+		if err := repo.MoveLevel(expert.ID, nil); err != nil {
+			t.Fatalf("Error moving players: %v", err)
+		}
+		alice, _ := repo.GetPlayerByID(aliceID)
+		if alice.Level != nil {
+			t.Errorf("Expected Alice to have no level, got %+v", alice.Level)
+		}
+	})
+}
+
+func TestPlayerRepository_LevelPerks(t *testing.T) {
+	playerRepositories(t, func(t *testing.T, repo PlayerRepository, levels LevelRepository) {
+		fee := models.MustParseMoney("5.00")
+		level := &models.Level{Name: "VIP", Perks: models.LevelPerks{
+			RoomIDs:                  []int{1, 3},
+			MaxWeeklyReservations:    4,
+			ChallengeFee:             &fee,
+			ChallengeCooldownSeconds: 10,
+		}}
+		if _, err := levels.Create(level); err != nil {
+			t.Fatalf("Error creating level: %v", err)
+		}
+		id, err := repo.CreatePlayer(models.Player{Name: "Alice", Level: level})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+		if _, err := repo.CreatePlayer(models.Player{Name: "Bob", Level: level}); err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		// Players come back with their level's perks
+		player, err := repo.GetPlayerByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if !levelsAreEqual(player.Level, level) {
+			t.Errorf("Expected level %+v, got %+v", level, player.Level)
+		}
+		players, err := repo.GetAllPlayers()
+		if err != nil {
+			t.Fatalf("Error fetching players: %v", err)
+		}
+		for _, player := range players {
+			if !levelsAreEqual(player.Level, level) {
+				t.Errorf("Expected %s at level %+v, got %+v", player.Name, level, player.Level)
+			}
+		}
+	})
 }
 
 // playersAreEqual checks if two players are equal considering their fields, including Level pointer.
@@ -130,7 +345,9 @@ func playersAreEqual(p1, p2 *models.Player) bool {
 	return p1.ID == p2.ID &&
 		p1.Name == p2.Name &&
 		levelsAreEqual(p1.Level, p2.Level) &&
-		p1.Balance == p2.Balance
+		p1.Balance == p2.Balance &&
+		p1.Role == p2.Role &&
+		p1.XP == p2.XP
 }
 
 // levelsAreEqual checks if two levels are equal.
@@ -138,5 +355,6 @@ func levelsAreEqual(l1, l2 *models.Level) bool {
 	if l1 == nil || l2 == nil {
 		return false
 	}
-	return l1.Name == l2.Name
+	return l1.ID == l2.ID && l1.Name == l2.Name && l1.Order == l2.Order && l1.MinXP == l2.MinXP &&
+		reflect.DeepEqual(l1.Perks, l2.Perks)
 }
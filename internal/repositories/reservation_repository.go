@@ -2,33 +2,82 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"oxo_game/internal/models"
 )
 
+var (
+	ErrReservationNotFound       = errors.New("reservation not found")
+	ErrReservationSeriesNotFound = errors.New("reservation series not found")
+)
+
 type ReservationRepository interface {
 	Create(reservation *models.Reservation) (int, error)
 	GetById(id int) (*models.Reservation, error)
+	// List, ListByRoom and ListByRoomAndDate return reservations oldest first.
 	List() []*models.Reservation
+	ListByRoom(roomID int) []*models.Reservation
 	ListByRoomAndDate(roomID int, date time.Time) []*models.Reservation
+	// ListByPlayer returns the player's reservations dated from from up to,
+	// but not including, to, in date order.
+	ListByPlayer(playerID int, from, to time.Time) []*models.Reservation
+	// ListByStatus returns the reservations in any of statuses, oldest first.
+	ListByStatus(statuses ...string) []*models.Reservation
+	// Update replaces the room, date, time and status of a reservation.
+	Update(reservation *models.Reservation) error
 	Delete(id int) error
+
+	CreateSeries(series *models.ReservationSeries) (int, error)
+	GetSeriesByID(id int) (*models.ReservationSeries, error)
+	// ListBySeries returns the occurrences of a series in date order.
+	ListBySeries(seriesID int) []*models.Reservation
 }
 
 type InMemoryReservationRepository struct {
+	*reservationStore
+	journal *undoJournal
+}
+
+type reservationStore struct {
 	mu           sync.RWMutex
 	reservations map[int]*models.Reservation
 	autoID       int
+	series       map[int]*models.ReservationSeries
+	seriesID     int
 }
 
 func NewInMemoryReservationRepository() *InMemoryReservationRepository {
 	return &InMemoryReservationRepository{
-		reservations: make(map[int]*models.Reservation),
-		autoID:       0,
+		reservationStore: &reservationStore{
+			reservations: make(map[int]*models.Reservation),
+			autoID:       0,
+			series:       make(map[int]*models.ReservationSeries),
+		},
 	}
 }
 
+func (r *InMemoryReservationRepository) withJournal(j *undoJournal) ReservationRepository {
+	return &InMemoryReservationRepository{reservationStore: r.reservationStore, journal: j}
+}
+
+// recordReservation records how to put the reservation back as it is now,
+// or remove it if it does not exist yet. The caller holds the write lock.
+func (r *InMemoryReservationRepository) recordReservation(id int) {
+	reservation, existed := r.reservations[id]
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.reservations[id] = reservation
+		} else {
+			delete(r.reservations, id)
+		}
+	})
+}
+
 func (r *InMemoryReservationRepository) Create(reservation *models.Reservation) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -36,6 +85,7 @@ func (r *InMemoryReservationRepository) Create(reservation *models.Reservation)
 	r.autoID++
 	reservation.ID = r.autoID
 	reservation.CreatedAt = time.Now()
+	r.recordReservation(reservation.ID)
 	r.reservations[reservation.ID] = reservation
 	return reservation.ID, nil
 }
@@ -46,41 +96,126 @@ func (r *InMemoryReservationRepository) GetById(id int) (*models.Reservation, er
 
 	reservation, ok := r.reservations[id]
 	if !ok {
-		return nil, errors.New("reservation not found")
+		return nil, ErrReservationNotFound
 	}
 	return reservation, nil
 }
 
 func (r *InMemoryReservationRepository) List() []*models.Reservation {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	return r.filter(func(*models.Reservation) bool { return true })
+}
 
-	reservations := make([]*models.Reservation, 0, len(r.reservations))
-	for _, reservation := range r.reservations {
-		reservations = append(reservations, reservation)
-	}
-	return reservations
+func (r *InMemoryReservationRepository) ListByRoom(roomID int) []*models.Reservation {
+	return r.filter(func(reservation *models.Reservation) bool {
+		return reservation.RoomID == roomID
+	})
 }
 
 func (r *InMemoryReservationRepository) ListByRoomAndDate(roomID int, date time.Time) []*models.Reservation {
+	return r.filter(func(reservation *models.Reservation) bool {
+		return reservation.RoomID == roomID && reservation.Date.Equal(date)
+	})
+}
+
+func (r *InMemoryReservationRepository) ListByPlayer(playerID int, from, to time.Time) []*models.Reservation {
+	reservations := r.filter(func(reservation *models.Reservation) bool {
+		return reservation.PlayerID == playerID && !reservation.Date.Before(from) && reservation.Date.Before(to)
+	})
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Date.Before(reservations[j].Date)
+	})
+	return reservations
+}
+
+// filter returns the reservations matching keep in ID order.
+func (r *InMemoryReservationRepository) filter(keep func(*models.Reservation) bool) []*models.Reservation {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var reservations []*models.Reservation
-	for _, reservation := range r.reservations {
-		if reservation.RoomID == roomID && reservation.Date.Equal(date) {
+	reservations := make([]*models.Reservation, 0)
+	for id := 1; id <= r.autoID; id++ {
+		if reservation, ok := r.reservations[id]; ok && keep(reservation) {
 			reservations = append(reservations, reservation)
 		}
 	}
 	return reservations
 }
+
+func (r *InMemoryReservationRepository) ListByStatus(statuses ...string) []*models.Reservation {
+	return r.filter(func(reservation *models.Reservation) bool {
+		for _, status := range statuses {
+			if reservation.Status == status {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *InMemoryReservationRepository) Update(reservation *models.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.reservations[reservation.ID]
+	if !ok {
+		return ErrReservationNotFound
+	}
+	updated := *existing
+	updated.RoomID = reservation.RoomID
+	updated.Date = reservation.Date
+	updated.Time = reservation.Time
+	updated.Status = reservation.Status
+	r.recordReservation(reservation.ID)
+	r.reservations[reservation.ID] = &updated
+	return nil
+}
+
 func (r *InMemoryReservationRepository) Delete(id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.reservations[id]; !ok {
-		return errors.New("reservation not found")
+		return ErrReservationNotFound
 	}
+	r.recordReservation(id)
 	delete(r.reservations, id)
 	return nil
 }
+
+func (r *InMemoryReservationRepository) CreateSeries(series *models.ReservationSeries) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seriesID++
+	series.ID = r.seriesID
+	series.CreatedAt = time.Now()
+	r.series[series.ID] = series
+	id := series.ID
+	r.journal.record(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.series, id)
+	})
+	return series.ID, nil
+}
+
+func (r *InMemoryReservationRepository) GetSeriesByID(id int) (*models.ReservationSeries, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	series, ok := r.series[id]
+	if !ok {
+		return nil, ErrReservationSeriesNotFound
+	}
+	return series, nil
+}
+
+func (r *InMemoryReservationRepository) ListBySeries(seriesID int) []*models.Reservation {
+	reservations := r.filter(func(reservation *models.Reservation) bool {
+		return reservation.SeriesID == seriesID
+	})
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Date.Before(reservations[j].Date)
+	})
+	return reservations
+}
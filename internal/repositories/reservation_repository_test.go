@@ -1,74 +1,235 @@
 package repositories
 
 import (
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
 	"oxo_game/internal/models"
 )
 
-func TestInMemoryReservationRepository_CRUDOperations(t *testing.T) {
-	repo := NewInMemoryReservationRepository()
+func reservationRepositories(t *testing.T, test func(t *testing.T, repo ReservationRepository)) {
+	forEachBackend(t,
+		func() ReservationRepository { return NewInMemoryReservationRepository() },
+		func(db *sql.DB) ReservationRepository { return NewSQLReservationRepository(db) },
+		test)
+}
 
-	// Create a reservation
-	reservation := &models.Reservation{
-		RoomID:   1,
-		Date:     time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC),
-		Time:     "10:00 AM",
-		PlayerID: 1,
-	}
+func TestReservationRepository_CRUDOperations(t *testing.T) {
+	reservationRepositories(t, func(t *testing.T, repo ReservationRepository) {
+		// Create a reservation
+		reservation := &models.Reservation{
+			RoomID:   1,
+			Date:     time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC),
+			Time:     "10:00 AM",
+			PlayerID: 1,
+			Status:   models.ReservationStatusPending,
+		}
 
-	id, err := repo.Create(reservation)
-	if err != nil {
-		t.Fatalf("Error creating reservation: %v", err)
-	}
+		id, err := repo.Create(reservation)
+		if err != nil {
+			t.Fatalf("Error creating reservation: %v", err)
+		}
 
-	// Set the expected ID and CreatedAt
-	reservation.ID = id
-	reservation.CreatedAt = time.Now()
+		// Create sets the expected ID and CreatedAt
+		if reservation.ID != id {
+			t.Fatalf("Expected reservation ID %d, got %d", id, reservation.ID)
+		}
 
-	// Get reservation by ID
-	createdReservation, err := repo.GetById(id)
-	if err != nil {
-		t.Fatalf("Error fetching reservation by ID: %v", err)
-	}
+		// Get reservation by ID
+		createdReservation, err := repo.GetById(id)
+		if err != nil {
+			t.Fatalf("Error fetching reservation by ID: %v", err)
+		}
 
-	// Check if the created reservation matches the expected reservation
-	if !reservationsAreEqual(reservation, createdReservation) {
-		t.Errorf("Created reservation does not match expected. Expected %+v, got %+v", reservation, createdReservation)
-	}
+		// Check if the created reservation matches the expected reservation
+		if !reservationsAreEqual(reservation, createdReservation) {
+			t.Errorf("Created reservation does not match expected. Expected %+v, got %+v", reservation, createdReservation)
+		}
 
-	// List all reservations
-	allReservations := repo.List()
-	if len(allReservations) != 1 {
-		t.Fatalf("Expected 1 reservation, got %d", len(allReservations))
-	}
-	if !reservationsAreEqual(reservation, allReservations[0]) {
-		t.Errorf("Listed reservation does not match expected. Expected %+v, got %+v", reservation, allReservations[0])
-	}
+		// List all reservations
+		allReservations := repo.List()
+		if len(allReservations) != 1 {
+			t.Fatalf("Expected 1 reservation, got %d", len(allReservations))
+		}
+		if !reservationsAreEqual(reservation, allReservations[0]) {
+			t.Errorf("Listed reservation does not match expected. Expected %+v, got %+v", reservation, allReservations[0])
+		}
 
-	// List reservations by room and date
-	roomDateReservations := repo.ListByRoomAndDate(reservation.RoomID, reservation.Date)
-	if len(roomDateReservations) != 1 {
-		t.Fatalf("Expected 1 reservation, got %d", len(roomDateReservations))
-	}
-	if !reservationsAreEqual(reservation, roomDateReservations[0]) {
-		t.Errorf("Room/date reservation does not match expected. Expected %+v, got %+v", reservation, roomDateReservations[0])
-	}
+		// List reservations by room
+		roomReservations := repo.ListByRoom(reservation.RoomID)
+		if len(roomReservations) != 1 || !reservationsAreEqual(reservation, roomReservations[0]) {
+			t.Errorf("Expected the room's reservation, got %+v", roomReservations)
+		}
+		if others := repo.ListByRoom(reservation.RoomID + 1); len(others) != 0 {
+			t.Errorf("Expected no reservations for another room, got %d", len(others))
+		}
 
-	// Delete the reservation
-	err = repo.Delete(reservation.ID)
-	if err != nil {
-		t.Fatalf("Error deleting reservation: %v", err)
-	}
+		// List reservations by room and date
+		roomDateReservations := repo.ListByRoomAndDate(reservation.RoomID, reservation.Date)
+		if len(roomDateReservations) != 1 {
+			t.Fatalf("Expected 1 reservation, got %d", len(roomDateReservations))
+		}
+		if !reservationsAreEqual(reservation, roomDateReservations[0]) {
+			t.Errorf("Room/date reservation does not match expected. Expected %+v, got %+v", reservation, roomDateReservations[0])
+		}
 
-	// Verify reservation deletion by trying to fetch it again
-	_, err = repo.GetById(id)
-	if err == nil {
-		t.Errorf("Expected reservation to be deleted, but it still exists")
-	} else if err.Error() != "reservation not found" {
-		t.Errorf("Expected 'reservation not found' error, got %v", err)
-	}
+		// Delete the reservation
+		err = repo.Delete(reservation.ID)
+		if err != nil {
+			t.Fatalf("Error deleting reservation: %v", err)
+		}
+
+		// Verify reservation deletion by trying to fetch it again
+		_, err = repo.GetById(id)
+		if err == nil {
+			t.Errorf("Expected reservation to be deleted, but it still exists")
+		} else if !errors.Is(err, ErrReservationNotFound) {
+			t.Errorf("Expected ErrReservationNotFound, got %v", err)
+		}
+	})
+}
+
+func TestReservationRepository_UpdateAndListByStatus(t *testing.T) {
+	reservationRepositories(t, func(t *testing.T, repo ReservationRepository) {
+		date := time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC)
+		for i, status := range []string{
+			models.ReservationStatusPending,
+			models.ReservationStatusConfirmed,
+			models.ReservationStatusCancelled,
+		} {
+			reservation := &models.Reservation{RoomID: 1, Date: date, Time: "10:00", PlayerID: i + 1, Status: status}
+			if _, err := repo.Create(reservation); err != nil {
+				t.Fatalf("Error creating reservation: %v", err)
+			}
+		}
+
+		active := repo.ListByStatus(models.ReservationStatusPending, models.ReservationStatusConfirmed)
+		if len(active) != 2 || active[0].PlayerID != 1 || active[1].PlayerID != 2 {
+			t.Fatalf("Expected the pending and confirmed reservations oldest first, got %+v", active)
+		}
+
+		// 改期并确认第一个预约
+		rescheduled := *active[0]
+		rescheduled.Date = date.AddDate(0, 0, 1)
+		rescheduled.Time = "16:00"
+		rescheduled.Status = models.ReservationStatusConfirmed
+		if err := repo.Update(&rescheduled); err != nil {
+			t.Fatalf("Error updating reservation: %v", err)
+		}
+
+		updated, err := repo.GetById(rescheduled.ID)
+		if err != nil {
+			t.Fatalf("Error fetching updated reservation: %v", err)
+		}
+		if !reservationsAreEqual(&rescheduled, updated) {
+			t.Errorf("Updated reservation does not match expected. Expected %+v, got %+v", rescheduled, updated)
+		}
+		if n := len(repo.ListByStatus(models.ReservationStatusPending)); n != 0 {
+			t.Errorf("Expected no pending reservations, got %d", n)
+		}
+
+		missing := rescheduled
+		missing.ID = 99
+		if err := repo.Update(&missing); !errors.Is(err, ErrReservationNotFound) {
+			t.Errorf("Expected ErrReservationNotFound, got %v", err)
+		}
+	})
+}
+
+func TestReservationRepository_Series(t *testing.T) {
+	reservationRepositories(t, func(t *testing.T, repo ReservationRepository) {
+		start := time.Date(2024, 9, 3, 0, 0, 0, 0, time.UTC)
+		series := &models.ReservationSeries{
+			RoomID:    1,
+			PlayerID:  1,
+			Time:      "19:00",
+			StartDate: start,
+			Rule:      "FREQ=WEEKLY;BYDAY=TU;COUNT=3",
+		}
+		id, err := repo.CreateSeries(series)
+		if err != nil {
+			t.Fatalf("Error creating series: %v", err)
+		}
+
+		stored, err := repo.GetSeriesByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching series: %v", err)
+		}
+		if stored.Rule != series.Rule || !stored.StartDate.Equal(start) || !stored.CreatedAt.Equal(series.CreatedAt) {
+			t.Errorf("Stored series does not match expected. Expected %+v, got %+v", series, stored)
+		}
+		if _, err := repo.GetSeriesByID(id + 1); !errors.Is(err, ErrReservationSeriesNotFound) {
+			t.Errorf("Expected ErrReservationSeriesNotFound, got %v", err)
+		}
+
+		// 倒序创建场次，再加一个不属于系列的预约
+		for week := 2; week >= 0; week-- {
+			occurrence := &models.Reservation{
+				RoomID:   1,
+				Date:     start.AddDate(0, 0, 7*week),
+				Time:     "19:00",
+				PlayerID: 1,
+				Status:   models.ReservationStatusPending,
+				SeriesID: id,
+			}
+			if _, err := repo.Create(occurrence); err != nil {
+				t.Fatalf("Error creating occurrence: %v", err)
+			}
+		}
+		single := &models.Reservation{RoomID: 1, Date: start, Time: "10:00", PlayerID: 2, Status: models.ReservationStatusPending}
+		if _, err := repo.Create(single); err != nil {
+			t.Fatalf("Error creating reservation: %v", err)
+		}
+
+		occurrences := repo.ListBySeries(id)
+		if len(occurrences) != 3 {
+			t.Fatalf("Expected 3 occurrences, got %d", len(occurrences))
+		}
+		for i, occurrence := range occurrences {
+			if want := start.AddDate(0, 0, 7*i); !occurrence.Date.Equal(want) || occurrence.SeriesID != id {
+				t.Errorf("Expected occurrence %d on %s in series %d, got %+v", i, want.Format("2006-01-02"), id, occurrence)
+			}
+		}
+
+		fetched, err := repo.GetById(single.ID)
+		if err != nil {
+			t.Fatalf("Error fetching reservation: %v", err)
+		}
+		if fetched.SeriesID != 0 {
+			t.Errorf("Expected no series, got %d", fetched.SeriesID)
+		}
+	})
+}
+
+func TestReservationRepository_ListByPlayer(t *testing.T) {
+	reservationRepositories(t, func(t *testing.T, repo ReservationRepository) {
+		monday := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+		// Created out of date order, with another player's in between
+		for _, r := range []struct {
+			days     int
+			playerID int
+		}{{8, 1}, {0, 1}, {3, 2}, {6, 1}, {7, 1}, {-1, 1}} {
+			reservation := &models.Reservation{RoomID: 1, Date: monday.AddDate(0, 0, r.days), Time: "10:00", PlayerID: r.playerID, Status: models.ReservationStatusPending}
+			if _, err := repo.Create(reservation); err != nil {
+				t.Fatalf("Error creating reservation: %v", err)
+			}
+		}
+
+		// The week from Monday to Sunday, in date order
+		week := repo.ListByPlayer(1, monday, monday.AddDate(0, 0, 7))
+		if len(week) != 2 {
+			t.Fatalf("Expected 2 reservations that week, got %d", len(week))
+		}
+		if !week[0].Date.Equal(monday) || !week[1].Date.Equal(monday.AddDate(0, 0, 6)) {
+			t.Errorf("Expected the reservations on Monday and Sunday, got %s and %s",
+				week[0].Date.Format("2006-01-02"), week[1].Date.Format("2006-01-02"))
+		}
+		if n := len(repo.ListByPlayer(2, monday, monday.AddDate(0, 0, 14))); n != 1 {
+			t.Errorf("Expected 1 reservation of player 2, got %d", n)
+		}
+	})
 }
 
 // reservationsAreEqual checks if two reservations are equal considering their fields.
@@ -81,5 +242,7 @@ func reservationsAreEqual(r1, r2 *models.Reservation) bool {
 		r1.Date.Equal(r2.Date) &&
 		r1.Time == r2.Time &&
 		r1.PlayerID == r2.PlayerID &&
+		r1.Status == r2.Status &&
+		r1.SeriesID == r2.SeriesID &&
 		r1.CreatedAt.Equal(r2.CreatedAt)
 }
@@ -2,13 +2,16 @@ package repositories
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"oxo_game/internal/models"
 )
 
 var (
-	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomNotFound        = errors.New("room not found")
+	ErrOccupantNotFound    = errors.New("player is not in a room")
+	ErrPlayerAlreadyInRoom = errors.New("player is already in a room")
 )
 
 // RoomRepository is the interface that wraps the basic CRUD operations for rooms.
@@ -18,20 +21,31 @@ type RoomRepository interface {
 	CreateRoom(room models.Room) (int, error)
 	UpdateRoom(id int, updatedRoom models.Room) error
 	DeleteRoom(id int) error
+	// AddOccupant records a player entering a room. A player is in at most
+	// one room at a time; ErrPlayerAlreadyInRoom is returned otherwise.
+	AddOccupant(occupant models.RoomOccupant) error
+	// RemoveOccupant records a player leaving whichever room they are in.
+	RemoveOccupant(playerID int) error
+	// GetOccupant returns the room a player is in, or ErrOccupantNotFound.
+	GetOccupant(playerID int) (*models.RoomOccupant, error)
+	// ListOccupants returns the players in a room, in the order they entered.
+	ListOccupants(roomID int) ([]models.RoomOccupant, error)
 }
 
 // InMemoryRoomRepository is an example of a repository using in-memory storage.
 type InMemoryRoomRepository struct {
-	mu     sync.RWMutex
-	rooms  map[int]models.Room
-	autoID int
+	mu        sync.RWMutex
+	rooms     map[int]models.Room
+	occupants map[int]models.RoomOccupant
+	autoID    int
 }
 
 // NewInMemoryRoomRepository creates a new InMemoryRoomRepository.
 func NewInMemoryRoomRepository() *InMemoryRoomRepository {
 	return &InMemoryRoomRepository{
-		rooms:  make(map[int]models.Room),
-		autoID: 0,
+		rooms:     make(map[int]models.Room),
+		occupants: make(map[int]models.RoomOccupant),
+		autoID:    0,
 	}
 }
 
@@ -89,3 +103,55 @@ func (r *InMemoryRoomRepository) DeleteRoom(id int) error {
 	delete(r.rooms, id)
 	return nil
 }
+
+// AddOccupant records a player entering a room.
+func (r *InMemoryRoomRepository) AddOccupant(occupant models.RoomOccupant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.occupants[occupant.PlayerID]; ok {
+		return ErrPlayerAlreadyInRoom
+	}
+	r.occupants[occupant.PlayerID] = occupant
+	return nil
+}
+
+// RemoveOccupant records a player leaving their room.
+func (r *InMemoryRoomRepository) RemoveOccupant(playerID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.occupants[playerID]; !ok {
+		return ErrOccupantNotFound
+	}
+	delete(r.occupants, playerID)
+	return nil
+}
+
+// GetOccupant returns the room a player is in.
+func (r *InMemoryRoomRepository) GetOccupant(playerID int) (*models.RoomOccupant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	occupant, ok := r.occupants[playerID]
+	if !ok {
+		return nil, ErrOccupantNotFound
+	}
+	return &occupant, nil
+}
+
+// ListOccupants returns the players in a room, in the order they entered.
+func (r *InMemoryRoomRepository) ListOccupants(roomID int) ([]models.RoomOccupant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	occupants := make([]models.RoomOccupant, 0)
+	for _, occupant := range r.occupants {
+		if occupant.RoomID == roomID {
+			occupants = append(occupants, occupant)
+		}
+	}
+	sort.Slice(occupants, func(i, j int) bool {
+		if !occupants[i].EnteredAt.Equal(occupants[j].EnteredAt) {
+			return occupants[i].EnteredAt.Before(occupants[j].EnteredAt)
+		}
+		return occupants[i].PlayerID < occupants[j].PlayerID
+	})
+	return occupants, nil
+}
@@ -1,84 +1,153 @@
 package repositories
 
 import (
+	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"oxo_game/internal/models"
 )
 
-func TestInMemoryRoomRepository_CRUDOperations(t *testing.T) {
-	repo := NewInMemoryRoomRepository()
+func roomRepositories(t *testing.T, test func(t *testing.T, repo RoomRepository)) {
+	forEachBackend(t,
+		func() RoomRepository { return NewInMemoryRoomRepository() },
+		func(db *sql.DB) RoomRepository { return NewSQLRoomRepository(db) },
+		test)
+}
 
-	// Create a room
-	room := models.Room{
-		Name:        "Room 1",
-		Description: "This is Room 1",
-		Status:      "Available",
-	}
+func TestRoomRepository_CRUDOperations(t *testing.T) {
+	roomRepositories(t, func(t *testing.T, repo RoomRepository) {
+		// Create a room
+		room := models.Room{
+			Name:        "Room 1",
+			Description: "This is Room 1",
+			Status:      "Available",
+			Capacity:    4,
+			SlotMinutes: 90,
+			OpensAt:     "10:00",
+			ClosesAt:    "23:30",
+		}
 
-	id, err := repo.CreateRoom(room)
-	if err != nil {
-		t.Fatalf("Error creating room: %v", err)
-	}
+		id, err := repo.CreateRoom(room)
+		if err != nil {
+			t.Fatalf("Error creating room: %v", err)
+		}
 
-	// Get room by ID
-	createdRoom, err := repo.GetRoomByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching room by ID: %v", err)
-	}
+		// Get room by ID
+		createdRoom, err := repo.GetRoomByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching room by ID: %v", err)
+		}
 
-	// Check if the created room matches the expected room
-	room.ID = id
-	if !roomsAreEqual(&room, createdRoom) {
-		t.Errorf("Created room does not match expected. Expected %+v, got %+v", room, createdRoom)
-	}
+		// Check if the created room matches the expected room
+		room.ID = id
+		if !roomsAreEqual(&room, createdRoom) {
+			t.Errorf("Created room does not match expected. Expected %+v, got %+v", room, createdRoom)
+		}
 
-	// Update room
-	updatedRoom := *createdRoom
-	updatedRoom.Name = "Updated Room 1"
-	updatedRoom.Status = "Occupied"
+		// Update room
+		updatedRoom := *createdRoom
+		updatedRoom.Name = "Updated Room 1"
+		updatedRoom.Status = "Occupied"
+		updatedRoom.Capacity = 2
+		updatedRoom.OpensAt = "08:00"
 
-	err = repo.UpdateRoom(id, updatedRoom)
-	if err != nil {
-		t.Fatalf("Error updating room: %v", err)
-	}
+		err = repo.UpdateRoom(id, updatedRoom)
+		if err != nil {
+			t.Fatalf("Error updating room: %v", err)
+		}
 
-	// Get updated room by ID
-	updatedRoomResult, err := repo.GetRoomByID(id)
-	if err != nil {
-		t.Fatalf("Error fetching updated room by ID: %v", err)
-	}
+		// Get updated room by ID
+		updatedRoomResult, err := repo.GetRoomByID(id)
+		if err != nil {
+			t.Fatalf("Error fetching updated room by ID: %v", err)
+		}
 
-	// Check if the updated room matches the expected updated room
-	if !roomsAreEqual(&updatedRoom, updatedRoomResult) {
-		t.Errorf("Updated room does not match expected. Expected %+v, got %+v", updatedRoom, updatedRoomResult)
-	}
+		// Check if the updated room matches the expected updated room
+		if !roomsAreEqual(&updatedRoom, updatedRoomResult) {
+			t.Errorf("Updated room does not match expected. Expected %+v, got %+v", updatedRoom, updatedRoomResult)
+		}
 
-	// List all rooms
-	allRooms, err := repo.GetAllRooms()
-	if err != nil {
-		t.Fatalf("Error listing all rooms: %v", err)
-	}
-	if len(allRooms) != 1 {
-		t.Fatalf("Expected 1 room, got %d", len(allRooms))
-	}
-	if !roomsAreEqual(&updatedRoom, &allRooms[0]) {
-		t.Errorf("Listed room does not match expected. Expected %+v, got %+v", updatedRoom, allRooms[0])
-	}
+		// List all rooms
+		allRooms, err := repo.GetAllRooms()
+		if err != nil {
+			t.Fatalf("Error listing all rooms: %v", err)
+		}
+		if len(allRooms) != 1 {
+			t.Fatalf("Expected 1 room, got %d", len(allRooms))
+		}
+		if !roomsAreEqual(&updatedRoom, &allRooms[0]) {
+			t.Errorf("Listed room does not match expected. Expected %+v, got %+v", updatedRoom, allRooms[0])
+		}
 
-	// Delete room
-	err = repo.DeleteRoom(id)
-	if err != nil {
-		t.Fatalf("Error deleting room: %v", err)
-	}
+		// Delete room
+		err = repo.DeleteRoom(id)
+		if err != nil {
+			t.Fatalf("Error deleting room: %v", err)
+		}
 
-	// Verify room deletion by trying to fetch it again
-	_, err = repo.GetRoomByID(id)
-	if err == nil {
-		t.Errorf("Expected room to be deleted, but it still exists")
-	} else if err != ErrRoomNotFound {
-		t.Errorf("Expected ErrRoomNotFound, got %v", err)
-	}
+		// Verify room deletion by trying to fetch it again
+		_, err = repo.GetRoomByID(id)
+		if err == nil {
+			t.Errorf("Expected room to be deleted, but it still exists")
+		} else if err != ErrRoomNotFound {
+			t.Errorf("Expected ErrRoomNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRoomRepository_Occupants(t *testing.T) {
+	roomRepositories(t, func(t *testing.T, repo RoomRepository) {
+		entered := time.Date(2024, 7, 20, 15, 0, 0, 0, time.UTC)
+		occupants := []models.RoomOccupant{
+			{RoomID: 1, PlayerID: 7, EnteredAt: entered.Add(time.Minute)},
+			{RoomID: 1, PlayerID: 3, EnteredAt: entered},
+			{RoomID: 2, PlayerID: 5, EnteredAt: entered},
+		}
+		for _, occupant := range occupants {
+			if err := repo.AddOccupant(occupant); err != nil {
+				t.Fatalf("Error adding occupant %d: %v", occupant.PlayerID, err)
+			}
+		}
+
+		// 同一玩家不能同时在两个房间
+		if err := repo.AddOccupant(models.RoomOccupant{RoomID: 2, PlayerID: 3, EnteredAt: entered}); !errors.Is(err, ErrPlayerAlreadyInRoom) {
+			t.Errorf("Expected ErrPlayerAlreadyInRoom, got %v", err)
+		}
+
+		listed, err := repo.ListOccupants(1)
+		if err != nil {
+			t.Fatalf("Error listing occupants: %v", err)
+		}
+		if len(listed) != 2 || listed[0].PlayerID != 3 || listed[1].PlayerID != 7 {
+			t.Fatalf("Expected players 3 and 7 in entry order, got %+v", listed)
+		}
+		if !listed[0].EnteredAt.Equal(entered) {
+			t.Errorf("Expected entered_at %v, got %v", entered, listed[0].EnteredAt)
+		}
+
+		occupant, err := repo.GetOccupant(5)
+		if err != nil {
+			t.Fatalf("Error fetching occupant: %v", err)
+		}
+		if occupant.RoomID != 2 {
+			t.Errorf("Expected player 5 in room 2, got room %d", occupant.RoomID)
+		}
+
+		if err := repo.RemoveOccupant(3); err != nil {
+			t.Fatalf("Error removing occupant: %v", err)
+		}
+		if _, err := repo.GetOccupant(3); !errors.Is(err, ErrOccupantNotFound) {
+			t.Errorf("Expected ErrOccupantNotFound after leaving, got %v", err)
+		}
+		if err := repo.RemoveOccupant(3); !errors.Is(err, ErrOccupantNotFound) {
+			t.Errorf("Expected ErrOccupantNotFound removing twice, got %v", err)
+		}
+		if listed, _ := repo.ListOccupants(1); len(listed) != 1 {
+			t.Errorf("Expected 1 occupant left in room 1, got %d", len(listed))
+		}
+	})
 }
 
 // roomsAreEqual checks if two rooms are equal considering their fields.
@@ -89,5 +158,9 @@ func roomsAreEqual(r1, r2 *models.Room) bool {
 	return r1.ID == r2.ID &&
 		r1.Name == r2.Name &&
 		r1.Description == r2.Description &&
-		r1.Status == r2.Status
+		r1.Status == r2.Status &&
+		r1.Capacity == r2.Capacity &&
+		r1.SlotMinutes == r2.SlotMinutes &&
+		r1.OpensAt == r2.OpensAt &&
+		r1.ClosesAt == r2.ClosesAt
 }
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// SQLAuthRepository stores credentials in the player_credentials table and
+// sessions in the sessions table.
+type SQLAuthRepository struct {
+	db dbtx
+}
+
+// NewSQLAuthRepository creates a new SQLAuthRepository.
+func NewSQLAuthRepository(db *sql.DB) *SQLAuthRepository {
+	return &SQLAuthRepository{db: db}
+}
+
+// CreateCredentials stores a player's credentials. CreatedAt is set to the
+// current time.
+func (r *SQLAuthRepository) CreateCredentials(credentials *models.Credentials) error {
+	if _, err := r.GetCredentialsByUsername(credentials.Username); err == nil {
+		return ErrUsernameTaken
+	} else if !errors.Is(err, ErrCredentialsNotFound) {
+		return err
+	}
+
+	credentials.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	_, err := r.db.Exec(`INSERT INTO player_credentials (player_id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`,
+		credentials.PlayerID, credentials.Username, credentials.PasswordHash, credentials.CreatedAt)
+	return err
+}
+
+// GetCredentialsByUsername returns the credentials with the given username.
+func (r *SQLAuthRepository) GetCredentialsByUsername(username string) (*models.Credentials, error) {
+	return r.getCredentials(`username = ?`, username)
+}
+
+// GetCredentialsByPlayerID returns the credentials of the given player.
+func (r *SQLAuthRepository) GetCredentialsByPlayerID(playerID int) (*models.Credentials, error) {
+	return r.getCredentials(`player_id = ?`, playerID)
+}
+
+func (r *SQLAuthRepository) getCredentials(where string, arg any) (*models.Credentials, error) {
+	var credentials models.Credentials
+	err := r.db.QueryRow(`SELECT player_id, username, password_hash, created_at FROM player_credentials WHERE `+where, arg).
+		Scan(&credentials.PlayerID, &credentials.Username, &credentials.PasswordHash, &credentials.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCredentialsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+// CreateSession stores a new session. Its times are stored to the second.
+func (r *SQLAuthRepository) CreateSession(session *models.Session) error {
+	session.CreatedAt = session.CreatedAt.UTC().Truncate(time.Second)
+	session.ExpiresAt = session.ExpiresAt.UTC().Truncate(time.Second)
+	_, err := r.db.Exec(`INSERT INTO sessions (token_hash, player_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		session.TokenHash, session.PlayerID, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+// GetSession returns the session with the given token hash.
+func (r *SQLAuthRepository) GetSession(tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.QueryRow(`SELECT token_hash, player_id, created_at, expires_at FROM sessions WHERE token_hash = ?`, tokenHash).
+		Scan(&session.TokenHash, &session.PlayerID, &session.CreatedAt, &session.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteSession deletes the session with the given token hash.
+func (r *SQLAuthRepository) DeleteSession(tokenHash string) error {
+	res, err := r.db.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrSessionNotFound)
+}
+
+// DeleteExpiredSessions removes the sessions that expired by now.
+func (r *SQLAuthRepository) DeleteExpiredSessions(now time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now.UTC().Truncate(time.Second))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeletePlayerAuth removes a player's credentials and all their sessions.
+func (r *SQLAuthRepository) DeletePlayerAuth(playerID int) error {
+	return inTx(r.db, func(tx dbtx) error {
+		if _, err := tx.Exec(`DELETE FROM sessions WHERE player_id = ?`, playerID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM player_credentials WHERE player_id = ?`, playerID)
+		return err
+	})
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+const challengeDefinitionColumns = `id, name, fee, win_probability, duration_seconds, cooldown_seconds, max_per_day, min_level_order, active_from, active_until`
+
+// SQLChallengeDefinitionRepository stores challenge definitions in the
+// challenge_definitions table.
+type SQLChallengeDefinitionRepository struct {
+	db dbtx
+}
+
+// NewSQLChallengeDefinitionRepository creates a new
+// SQLChallengeDefinitionRepository.
+func NewSQLChallengeDefinitionRepository(db *sql.DB) *SQLChallengeDefinitionRepository {
+	return &SQLChallengeDefinitionRepository{db: db}
+}
+
+func (r *SQLChallengeDefinitionRepository) Create(definition *models.ChallengeDefinition) (int, error) {
+	res, err := r.db.Exec(`INSERT INTO challenge_definitions (name, fee, win_probability, duration_seconds, cooldown_seconds, max_per_day, min_level_order, active_from, active_until) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		definition.Name, definition.Fee, definition.WinProbability, definition.DurationSeconds, definition.CooldownSeconds,
+		definition.MaxPerDay, definition.MinLevelOrder, nullTime(definition.ActiveFrom), nullTime(definition.ActiveUntil))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	definition.ID = int(id)
+	return definition.ID, nil
+}
+
+func (r *SQLChallengeDefinitionRepository) GetById(id int) (*models.ChallengeDefinition, error) {
+	row := r.db.QueryRow(`SELECT `+challengeDefinitionColumns+` FROM challenge_definitions WHERE id = ?`, id)
+	definition, err := scanChallengeDefinition(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChallengeDefinitionNotFound
+	}
+	return definition, err
+}
+
+func (r *SQLChallengeDefinitionRepository) List() []*models.ChallengeDefinition {
+	definitions := make([]*models.ChallengeDefinition, 0)
+	rows, err := r.db.Query(`SELECT ` + challengeDefinitionColumns + ` FROM challenge_definitions ORDER BY id`)
+	if err != nil {
+		return definitions
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		definition, err := scanChallengeDefinition(rows)
+		if err != nil {
+			return definitions
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions
+}
+
+func (r *SQLChallengeDefinitionRepository) Update(definition *models.ChallengeDefinition) error {
+	res, err := r.db.Exec(`UPDATE challenge_definitions SET name = ?, fee = ?, win_probability = ?, duration_seconds = ?, cooldown_seconds = ?, max_per_day = ?, min_level_order = ?, active_from = ?, active_until = ? WHERE id = ?`,
+		definition.Name, definition.Fee, definition.WinProbability, definition.DurationSeconds, definition.CooldownSeconds,
+		definition.MaxPerDay, definition.MinLevelOrder, nullTime(definition.ActiveFrom), nullTime(definition.ActiveUntil), definition.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrChallengeDefinitionNotFound)
+}
+
+func (r *SQLChallengeDefinitionRepository) Delete(id int) error {
+	res, err := r.db.Exec(`DELETE FROM challenge_definitions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrChallengeDefinitionNotFound)
+}
+
+func scanChallengeDefinition(row rowScanner) (*models.ChallengeDefinition, error) {
+	var (
+		definition              models.ChallengeDefinition
+		activeFrom, activeUntil sql.NullTime
+	)
+	err := row.Scan(&definition.ID, &definition.Name, &definition.Fee, &definition.WinProbability,
+		&definition.DurationSeconds, &definition.CooldownSeconds, &definition.MaxPerDay, &definition.MinLevelOrder, &activeFrom, &activeUntil)
+	if err != nil {
+		return nil, err
+	}
+	if activeFrom.Valid {
+		definition.ActiveFrom = &activeFrom.Time
+	}
+	if activeUntil.Valid {
+		definition.ActiveUntil = &activeUntil.Time
+	}
+	return &definition, nil
+}
+
+// nullTime stores a missing time as NULL. TIMESTAMP columns only keep whole
+// seconds, in UTC.
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// SQLChallengeRepository stores challenges in the challenges table.
+type SQLChallengeRepository struct {
+	db dbtx
+}
+
+// NewSQLChallengeRepository creates a new SQLChallengeRepository.
+func NewSQLChallengeRepository(db *sql.DB) *SQLChallengeRepository {
+	return &SQLChallengeRepository{db: db}
+}
+
+func (r *SQLChallengeRepository) Create(challenge *models.Challenge) (int, error) {
+	// TIMESTAMP columns only keep whole seconds
+	challenge.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.db.Exec(`INSERT INTO challenges (player_id, definition_id, created_at, won) VALUES (?, ?, ?, ?)`,
+		challenge.PlayerID, challenge.DefinitionID, challenge.CreatedAt, challenge.Won)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	challenge.ID = int(id)
+	return challenge.ID, nil
+}
+
+func (r *SQLChallengeRepository) GetById(id int) (*models.Challenge, error) {
+	row := r.db.QueryRow(`SELECT id, player_id, definition_id, created_at, won FROM challenges WHERE id = ?`, id)
+	challenge, err := scanChallenge(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChallengeNotFound
+	}
+	return challenge, err
+}
+
+func (r *SQLChallengeRepository) ListByPlayer(playerID int) []*models.Challenge {
+	return r.query(`SELECT id, player_id, definition_id, created_at, won FROM challenges WHERE player_id = ? ORDER BY id`, playerID)
+}
+
+func (r *SQLChallengeRepository) ListLatest(n int) []*models.Challenge {
+	return r.query(`SELECT id, player_id, definition_id, created_at, won FROM challenges ORDER BY id DESC LIMIT ?`, n)
+}
+
+func (r *SQLChallengeRepository) query(query string, args ...any) []*models.Challenge {
+	var challenges []*models.Challenge
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return challenges
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		challenge, err := scanChallenge(rows)
+		if err != nil {
+			return challenges
+		}
+		challenges = append(challenges, challenge)
+	}
+	return challenges
+}
+
+func scanChallenge(row rowScanner) (*models.Challenge, error) {
+	var challenge models.Challenge
+	if err := row.Scan(&challenge.ID, &challenge.PlayerID, &challenge.DefinitionID, &challenge.CreatedAt, &challenge.Won); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
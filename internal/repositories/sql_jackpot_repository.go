@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// SQLJackpotRepository stores the jackpot in the jackpot_pools and
+// jackpot_payouts tables.
+type SQLJackpotRepository struct {
+	db dbtx
+}
+
+// NewSQLJackpotRepository creates a new SQLJackpotRepository.
+func NewSQLJackpotRepository(db *sql.DB) *SQLJackpotRepository {
+	return &SQLJackpotRepository{db: db}
+}
+
+// GetPool returns the jackpot pool, or ErrJackpotPoolNotFound before it has
+// first been saved.
+func (r *SQLJackpotRepository) GetPool() (*models.JackpotPool, error) {
+	var pool models.JackpotPool
+	err := r.db.QueryRow(`SELECT id, balance, updated_at FROM jackpot_pools WHERE id = ?`, jackpotPoolID).
+		Scan(&pool.ID, &pool.Balance, &pool.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJackpotPoolNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// CreatePool creates the jackpot pool. Inserting it a second time fails on
+// the primary key.
+func (r *SQLJackpotRepository) CreatePool(balance models.Money) error {
+	_, err := r.db.Exec(`INSERT INTO jackpot_pools (id, balance, updated_at) VALUES (?, ?, ?)`,
+		jackpotPoolID, balance, time.Now().Unix())
+	return err
+}
+
+// AddToPool adds amount to the pool's balance in a single statement.
+func (r *SQLJackpotRepository) AddToPool(amount models.Money) error {
+	res, err := r.db.Exec(`UPDATE jackpot_pools SET balance = balance + ?, updated_at = ? WHERE id = ?`,
+		amount, time.Now().Unix(), jackpotPoolID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrJackpotPoolNotFound)
+}
+
+// ResetPool sets the pool's balance and returns the balance it replaced.
+func (r *SQLJackpotRepository) ResetPool(balance models.Money) (models.Money, error) {
+	var replaced models.Money
+	err := inTx(r.db, func(tx dbtx) error {
+		// Writing the row first locks it until the transaction ends, so no
+		// other payout or contribution can slip in between the read and
+		// the reset
+		res, err := tx.Exec(`UPDATE jackpot_pools SET updated_at = ? WHERE id = ?`, time.Now().Unix(), jackpotPoolID)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(res, ErrJackpotPoolNotFound); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(`SELECT balance FROM jackpot_pools WHERE id = ?`, jackpotPoolID).Scan(&replaced); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE jackpot_pools SET balance = ? WHERE id = ?`, balance, jackpotPoolID)
+		return err
+	})
+	if err != nil {
+		return models.Money{}, err
+	}
+	return replaced, nil
+}
+
+// CreatePayout records a payout and returns its ID.
+func (r *SQLJackpotRepository) CreatePayout(payout *models.JackpotPayout) (int, error) {
+	payout.CreatedAt = time.Now().Unix()
+
+	res, err := r.db.Exec(`INSERT INTO jackpot_payouts (player_id, challenge_id, amount, created_at) VALUES (?, ?, ?, ?)`,
+		payout.PlayerID, payout.ChallengeID, payout.Amount, payout.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	payout.ID = int(id)
+	return payout.ID, nil
+}
+
+// ListPayouts returns the latest n payouts, newest first.
+func (r *SQLJackpotRepository) ListPayouts(n int) []*models.JackpotPayout {
+	payouts := make([]*models.JackpotPayout, 0)
+	rows, err := r.db.Query(`SELECT id, player_id, challenge_id, amount, created_at FROM jackpot_payouts ORDER BY id DESC LIMIT ?`, n)
+	if err != nil {
+		return payouts
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payout models.JackpotPayout
+		if err := rows.Scan(&payout.ID, &payout.PlayerID, &payout.ChallengeID, &payout.Amount, &payout.CreatedAt); err != nil {
+			return payouts
+		}
+		payouts = append(payouts, &payout)
+	}
+	return payouts
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// SQLLedgerRepository stores the ledger in the ledger_transactions and
+// ledger_entries tables.
+type SQLLedgerRepository struct {
+	db dbtx
+}
+
+// NewSQLLedgerRepository creates a new SQLLedgerRepository.
+func NewSQLLedgerRepository(db *sql.DB) *SQLLedgerRepository {
+	return &SQLLedgerRepository{db: db}
+}
+
+// Post appends a transaction and returns its ID.
+func (r *SQLLedgerRepository) Post(transaction *models.LedgerTransaction) (int, error) {
+	transaction.CreatedAt = time.Now().Unix()
+
+	res, err := r.db.Exec(`INSERT INTO ledger_transactions (kind, memo, reference, created_at) VALUES (?, ?, ?, ?)`,
+		transaction.Kind, transaction.Memo, transaction.Reference, transaction.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	transaction.ID = int(id)
+
+	for i := range transaction.Entries {
+		entry := &transaction.Entries[i]
+		entry.TransactionID = transaction.ID
+		res, err := r.db.Exec(`INSERT INTO ledger_entries (transaction_id, account, amount) VALUES (?, ?, ?)`,
+			entry.TransactionID, entry.Account, entry.Amount)
+		if err != nil {
+			return 0, err
+		}
+		entryID, err := res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		entry.ID = int(entryID)
+	}
+	return transaction.ID, nil
+}
+
+// ListByAccount returns the transactions that touched account, oldest first.
+func (r *SQLLedgerRepository) ListByAccount(account string) ([]*models.LedgerTransaction, error) {
+	rows, err := r.db.Query(`SELECT t.id, t.kind, t.memo, t.reference, t.created_at, e.id, e.account, e.amount
+FROM ledger_transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+WHERE t.id IN (SELECT transaction_id FROM ledger_entries WHERE account = ?)
+ORDER BY t.id, e.id`, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]*models.LedgerTransaction, 0)
+	var current *models.LedgerTransaction
+	for rows.Next() {
+		var (
+			transaction models.LedgerTransaction
+			entry       models.LedgerEntry
+		)
+		err := rows.Scan(&transaction.ID, &transaction.Kind, &transaction.Memo, &transaction.Reference,
+			&transaction.CreatedAt, &entry.ID, &entry.Account, &entry.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || current.ID != transaction.ID {
+			current = &transaction
+			transactions = append(transactions, current)
+		}
+		entry.TransactionID = current.ID
+		current.Entries = append(current.Entries, entry)
+	}
+	return transactions, rows.Err()
+}
+
+// AccountBalance returns the sum of every entry posted to account.
+func (r *SQLLedgerRepository) AccountBalance(account string) (models.Money, error) {
+	var balance models.Money
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = ?`, account).
+		Scan(&balance)
+	return balance, err
+}
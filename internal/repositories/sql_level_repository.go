@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"oxo_game/internal/models"
+)
+
+const levelColumns = `id, name, sort_order, min_xp, max_weekly_reservations, challenge_fee, challenge_cooldown_seconds`
+
+// SQLLevelRepository stores levels in the levels table and the rooms each
+// level may reserve in level_rooms.
+type SQLLevelRepository struct {
+	db dbtx
+}
+
+// NewSQLLevelRepository creates a new SQLLevelRepository.
+func NewSQLLevelRepository(db *sql.DB) *SQLLevelRepository {
+	return &SQLLevelRepository{db: db}
+}
+
+func (r *SQLLevelRepository) Create(level *models.Level) (int, error) {
+	err := inTx(r.db, func(tx dbtx) error {
+		perks := level.Perks
+		res, err := tx.Exec(`INSERT INTO levels (name, sort_order, min_xp, max_weekly_reservations, challenge_fee, challenge_cooldown_seconds) VALUES (?, ?, ?, ?, ?, ?)`,
+			level.Name, level.Order, level.MinXP, perks.MaxWeeklyReservations, nullMoney(perks.ChallengeFee), perks.ChallengeCooldownSeconds)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		level.ID = int(id)
+		return saveLevelRooms(tx, level)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return level.ID, nil
+}
+
+func (r *SQLLevelRepository) GetById(id int) (*models.Level, error) {
+	level, err := scanLevel(r.db.QueryRow(`SELECT `+levelColumns+` FROM levels WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLevelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadRooms(level); err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+func (r *SQLLevelRepository) List() []*models.Level {
+	levels := make([]*models.Level, 0)
+	rows, err := r.db.Query(`SELECT ` + levelColumns + ` FROM levels ORDER BY sort_order, id`)
+	if err != nil {
+		return levels
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		level, err := scanLevel(rows)
+		if err != nil {
+			return levels
+		}
+		levels = append(levels, level)
+	}
+	rows.Close()
+	r.loadRooms(levels...)
+	return levels
+}
+
+func (r *SQLLevelRepository) Update(level *models.Level) error {
+	return inTx(r.db, func(tx dbtx) error {
+		perks := level.Perks
+		res, err := tx.Exec(`UPDATE levels SET name = ?, sort_order = ?, min_xp = ?, max_weekly_reservations = ?, challenge_fee = ?, challenge_cooldown_seconds = ? WHERE id = ?`,
+			level.Name, level.Order, level.MinXP, perks.MaxWeeklyReservations, nullMoney(perks.ChallengeFee), perks.ChallengeCooldownSeconds, level.ID)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(res, ErrLevelNotFound); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM level_rooms WHERE level_id = ?`, level.ID); err != nil {
+			return err
+		}
+		return saveLevelRooms(tx, level)
+	})
+}
+
+func (r *SQLLevelRepository) Delete(id int) error {
+	return inTx(r.db, func(tx dbtx) error {
+		res, err := tx.Exec(`DELETE FROM levels WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(res, ErrLevelNotFound); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM level_rooms WHERE level_id = ?`, id)
+		return err
+	})
+}
+
+// loadRooms fills in the rooms the levels may reserve.
+func (r *SQLLevelRepository) loadRooms(levels ...*models.Level) error {
+	byID := make(map[int][]*models.Level, len(levels))
+	for _, level := range levels {
+		byID[level.ID] = append(byID[level.ID], level)
+	}
+	query, args := `SELECT level_id, room_id FROM level_rooms ORDER BY level_id, room_id`, []any(nil)
+	if len(byID) == 1 {
+		query, args = `SELECT level_id, room_id FROM level_rooms WHERE level_id = ? ORDER BY room_id`, []any{levels[0].ID}
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var levelID, roomID int
+		if err := rows.Scan(&levelID, &roomID); err != nil {
+			return err
+		}
+		for _, level := range byID[levelID] {
+			level.Perks.RoomIDs = append(level.Perks.RoomIDs, roomID)
+		}
+	}
+	return rows.Err()
+}
+
+func saveLevelRooms(tx dbtx, level *models.Level) error {
+	for _, roomID := range level.Perks.RoomIDs {
+		if _, err := tx.Exec(`INSERT INTO level_rooms (level_id, room_id) VALUES (?, ?)`, level.ID, roomID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanLevel(row rowScanner) (*models.Level, error) {
+	var (
+		level models.Level
+		fee   sql.Null[models.Money]
+	)
+	err := row.Scan(&level.ID, &level.Name, &level.Order, &level.MinXP,
+		&level.Perks.MaxWeeklyReservations, &fee, &level.Perks.ChallengeCooldownSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Valid {
+		level.Perks.ChallengeFee = &fee.V
+	}
+	return &level, nil
+}
+
+// nullMoney stores a missing amount as NULL.
+func nullMoney(m *models.Money) any {
+	if m == nil {
+		return nil
+	}
+	return *m
+}
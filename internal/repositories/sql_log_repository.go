@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"oxo_game/internal/models"
+)
+
+const logColumns = `id, player_id, action, details, timestamp, created_at, updated_at`
+
+// SQLLogRepository stores logs in the logs table.
+type SQLLogRepository struct {
+	db dbtx
+}
+
+// NewSQLLogRepository creates a new SQLLogRepository.
+func NewSQLLogRepository(db *sql.DB) *SQLLogRepository {
+	return &SQLLogRepository{db: db}
+}
+
+// GetAllLogs returns all logs.
+func (r *SQLLogRepository) GetAllLogs() ([]models.Log, error) {
+	return r.query(`SELECT ` + logColumns + ` FROM logs ORDER BY id`)
+}
+
+// GetLogByID returns the log with the given ID.
+func (r *SQLLogRepository) GetLogByID(id int) (*models.Log, error) {
+	row := r.db.QueryRow(`SELECT `+logColumns+` FROM logs WHERE id = ?`, id)
+	log, err := scanLog(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLogNotFound
+	}
+	return log, err
+}
+
+// CreateLog adds a new log and returns the new log's ID. Its details are
+// stored as JSON and indexed in log_details.
+func (r *SQLLogRepository) CreateLog(log models.Log) (int, error) {
+	ids, err := r.CreateLogs([]models.Log{log})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// CreateLogs adds several logs in one transaction and returns their IDs.
+func (r *SQLLogRepository) CreateLogs(logs []models.Log) ([]int, error) {
+	ids := make([]int, len(logs))
+	err := inTx(r.db, func(tx dbtx) error {
+		for i, log := range logs {
+			details, err := json.Marshal(cloneDetails(log.Details))
+			if err != nil {
+				return err
+			}
+			res, err := tx.Exec(`INSERT INTO logs (player_id, action, details, timestamp, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				log.PlayerID, log.Action, string(details), log.Timestamp, log.CreatedAt, log.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			for name, value := range log.Details {
+				_, err := tx.Exec(`INSERT INTO log_details (log_id, name, value) VALUES (?, ?, ?)`,
+					id, name, models.LogDetailText(value))
+				if err != nil {
+					return err
+				}
+			}
+			ids[i] = int(id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetLogsByPlayerID returns logs for a specific player ID.
+func (r *SQLLogRepository) GetLogsByPlayerID(playerID int) ([]models.Log, error) {
+	return r.query(`SELECT `+logColumns+` FROM logs WHERE player_id = ? ORDER BY id`, playerID)
+}
+
+// GetLogsByAction returns logs for a specific action.
+func (r *SQLLogRepository) GetLogsByAction(action models.LogAction) ([]models.Log, error) {
+	return r.query(`SELECT `+logColumns+` FROM logs WHERE action = ? ORDER BY id`, action)
+}
+
+// GetLogsByTimeRange returns logs within a specific time range.
+func (r *SQLLogRepository) GetLogsByTimeRange(startTime, endTime int64) ([]models.Log, error) {
+	return r.query(`SELECT `+logColumns+` FROM logs WHERE timestamp >= ? AND timestamp <= ? ORDER BY id`,
+		startTime, endTime)
+}
+
+// Query returns the logs matching q in (timestamp, id) order.
+func (r *SQLLogRepository) Query(q LogQuery) ([]models.Log, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if q.PlayerID != 0 {
+		where = append(where, `player_id = ?`)
+		args = append(args, q.PlayerID)
+	}
+	if q.Action != "" {
+		where = append(where, `action = ?`)
+		args = append(args, q.Action)
+	}
+	if q.StartTime != 0 {
+		where = append(where, `timestamp >= ?`)
+		args = append(args, q.StartTime)
+	}
+	if q.EndTime != 0 {
+		where = append(where, `timestamp <= ?`)
+		args = append(args, q.EndTime)
+	}
+	order, after := `ASC`, `>`
+	if q.Descending {
+		order, after = `DESC`, `<`
+	}
+	for name, value := range q.Details {
+		where = append(where, `EXISTS (SELECT 1 FROM log_details d WHERE d.log_id = logs.id AND d.name = ? AND d.value = ?)`)
+		args = append(args, name, value)
+	}
+	if q.After != nil {
+		where = append(where, `(timestamp `+after+` ? OR (timestamp = ? AND id `+after+` ?))`)
+		args = append(args, q.After.Timestamp, q.After.Timestamp, q.After.ID)
+	}
+
+	query := `SELECT ` + logColumns + ` FROM logs`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY timestamp ` + order + `, id ` + order
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+	return r.query(query, args...)
+}
+
+// DeleteLog deletes the log with the given ID.
+func (r *SQLLogRepository) DeleteLog(id int) error {
+	return inTx(r.db, func(tx dbtx) error {
+		if _, err := tx.Exec(`DELETE FROM log_details WHERE log_id = ?`, id); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM logs WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		return requireAffected(res, ErrLogNotFound)
+	})
+}
+
+// deleteBatchSize caps the IDs in one DELETE statement.
+const deleteBatchSize = 500
+
+// DeleteLogs deletes the logs with the given IDs and returns how many there
+// were.
+func (r *SQLLogRepository) DeleteLogs(ids []int) (int, error) {
+	deleted := 0
+	err := inTx(r.db, func(tx dbtx) error {
+		for len(ids) > 0 {
+			batch := ids[:min(len(ids), deleteBatchSize)]
+			ids = ids[len(batch):]
+
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+			args := make([]any, len(batch))
+			for i, id := range batch {
+				args[i] = id
+			}
+			if _, err := tx.Exec(`DELETE FROM log_details WHERE log_id IN (`+placeholders+`)`, args...); err != nil {
+				return err
+			}
+			res, err := tx.Exec(`DELETE FROM logs WHERE id IN (`+placeholders+`)`, args...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (r *SQLLogRepository) query(query string, args ...any) ([]models.Log, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.Log
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, *log)
+	}
+	return logs, rows.Err()
+}
+
+func scanLog(row rowScanner) (*models.Log, error) {
+	var (
+		log     models.Log
+		details sql.NullString
+	)
+	if err := row.Scan(&log.ID, &log.PlayerID, &log.Action, &details, &log.Timestamp, &log.CreatedAt, &log.UpdatedAt); err != nil {
+		return nil, err
+	}
+	log.Details = parseDetails(details.String)
+	return &log, nil
+}
+
+// parseDetails reads the details column. Logs written before details were
+// structured hold free text, which becomes the note.
+func parseDetails(text string) models.LogDetails {
+	details := models.LogDetails{}
+	if text == "" {
+		return details
+	}
+	if err := json.Unmarshal([]byte(text), &details); err != nil || details == nil {
+		return models.LogDetails{models.LogDetailNote: text}
+	}
+	return details
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+const paymentColumns = `id, player_id, type, method, amount, details, created_at`
+
+// SQLPaymentRepository stores payments in the payments table.
+type SQLPaymentRepository struct {
+	db dbtx
+}
+
+// NewSQLPaymentRepository creates a new SQLPaymentRepository.
+func NewSQLPaymentRepository(db *sql.DB) *SQLPaymentRepository {
+	return &SQLPaymentRepository{db: db}
+}
+
+func (r *SQLPaymentRepository) Create(payment *models.Payment) (int, error) {
+	payment.CreatedAt = time.Now().Unix()
+
+	res, err := r.db.Exec(`INSERT INTO payments (player_id, type, method, amount, details, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		payment.PlayerID, payment.Type, payment.Method, payment.Amount, payment.Details, payment.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	payment.ID = int(id)
+	return payment.ID, nil
+}
+
+func (r *SQLPaymentRepository) GetById(id int) (*models.Payment, error) {
+	row := r.db.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE id = ?`, id)
+	payment, err := scanPayment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+// ListByPlayer returns the player's payments, oldest first.
+func (r *SQLPaymentRepository) ListByPlayer(playerID int) []*models.Payment {
+	payments := make([]*models.Payment, 0)
+	rows, err := r.db.Query(`SELECT `+paymentColumns+` FROM payments WHERE player_id = ? ORDER BY id`, playerID)
+	if err != nil {
+		return payments
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return payments
+		}
+		payments = append(payments, payment)
+	}
+	return payments
+}
+
+func scanPayment(row rowScanner) (*models.Payment, error) {
+	var (
+		payment models.Payment
+		details sql.NullString
+	)
+	err := row.Scan(&payment.ID, &payment.PlayerID, &payment.Type, &payment.Method, &payment.Amount,
+		&details, &payment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	payment.Details = details.String
+	return &payment, nil
+}
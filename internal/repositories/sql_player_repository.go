@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"oxo_game/internal/models"
+)
+
+const playerQuery = `SELECT p.id, p.name, p.balance, p.role, p.xp, l.id, l.name, l.sort_order, l.min_xp,
+l.max_weekly_reservations, l.challenge_fee, l.challenge_cooldown_seconds
+FROM players p LEFT JOIN levels l ON l.id = p.level_id`
+
+// SQLPlayerRepository stores players in the players table. A player's level is
+// a reference to a row in the levels table.
+type SQLPlayerRepository struct {
+	db dbtx
+}
+
+// NewSQLPlayerRepository creates a new SQLPlayerRepository.
+func NewSQLPlayerRepository(db *sql.DB) *SQLPlayerRepository {
+	return &SQLPlayerRepository{db: db}
+}
+
+// GetAllPlayers returns all players.
+func (r *SQLPlayerRepository) GetAllPlayers() ([]models.Player, error) {
+	return r.queryPlayers(playerQuery + ` ORDER BY p.id`)
+}
+
+// GetPlayersByLevel returns the players at the level.
+func (r *SQLPlayerRepository) GetPlayersByLevel(levelID int) ([]models.Player, error) {
+	return r.queryPlayers(playerQuery+` WHERE p.level_id = ? ORDER BY p.id`, levelID)
+}
+
+func (r *SQLPlayerRepository) queryPlayers(query string, args ...any) ([]models.Player, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := make([]models.Player, 0)
+	for rows.Next() {
+		player, err := scanPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, *player)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	levels := make([]*models.Level, 0, len(players))
+	for _, player := range players {
+		if player.Level != nil {
+			levels = append(levels, player.Level)
+		}
+	}
+	if len(levels) == 0 {
+		return players, nil
+	}
+	return players, r.levels().loadRooms(levels...)
+}
+
+// GetPlayerByID returns the player with the given ID.
+func (r *SQLPlayerRepository) GetPlayerByID(id int) (*models.Player, error) {
+	row := r.db.QueryRow(playerQuery+` WHERE p.id = ?`, id)
+	player, err := scanPlayer(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+	if err != nil || player.Level == nil {
+		return player, err
+	}
+	return player, r.levels().loadRooms(player.Level)
+}
+
+// levels returns a level repository sharing the player repository's
+// connection or transaction.
+func (r *SQLPlayerRepository) levels() *SQLLevelRepository {
+	return &SQLLevelRepository{db: r.db}
+}
+
+// CreatePlayer adds a new player and returns the new player's ID.
+func (r *SQLPlayerRepository) CreatePlayer(player models.Player) (int, error) {
+	res, err := r.db.Exec(`INSERT INTO players (name, level_id, balance, role, xp) VALUES (?, ?, ?, ?, ?)`,
+		player.Name, levelID(player.Level), player.Balance, player.Role, player.XP)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdatePlayer updates the name, level and role of the player with the given
+// ID. Balance and experience are not written, so a DeductBalance,
+// CreditBalance or AddXP committed since the player was read is kept.
+func (r *SQLPlayerRepository) UpdatePlayer(id int, updatedPlayer models.Player) error {
+	res, err := r.db.Exec(`UPDATE players SET name = ?, level_id = ?, role = ? WHERE id = ?`,
+		updatedPlayer.Name, levelID(updatedPlayer.Level), updatedPlayer.Role, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrPlayerNotFound)
+}
+
+// MoveLevel moves every player at level from to level to.
+func (r *SQLPlayerRepository) MoveLevel(from int, to *models.Level) error {
+	_, err := r.db.Exec(`UPDATE players SET level_id = ? WHERE level_id = ?`, levelID(to), from)
+	return err
+}
+
+// DeletePlayer deletes the player with the given ID.
+func (r *SQLPlayerRepository) DeletePlayer(id int) error {
+	res, err := r.db.Exec(`DELETE FROM players WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrPlayerNotFound)
+}
+
+// DeductBalance subtracts amount from the player's balance in a single
+// statement, so concurrent deductions can never overdraw the account. SQLite
+// keeps DECIMAL columns as REAL, so results are rounded back to cents.
+func (r *SQLPlayerRepository) DeductBalance(playerID int, amount models.Money) error {
+	res, err := r.db.Exec(`UPDATE players SET balance = ROUND(balance - ?, 2) WHERE id = ? AND ROUND(balance - ?, 2) >= 0`,
+		amount, playerID, amount)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := r.GetPlayerByID(playerID); err != nil {
+		return err
+	}
+	return ErrInsufficientBalance
+}
+
+// CreditBalance adds amount to the player's balance.
+func (r *SQLPlayerRepository) CreditBalance(playerID int, amount models.Money) error {
+	res, err := r.db.Exec(`UPDATE players SET balance = ROUND(balance + ?, 2) WHERE id = ?`, amount, playerID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrPlayerNotFound)
+}
+
+// AddXP adds xp, which may be negative, to the player's experience in a
+// single statement and returns the new total. Experience never drops below 0.
+func (r *SQLPlayerRepository) AddXP(playerID int, xp int64) (int64, error) {
+	res, err := r.db.Exec(`UPDATE players SET xp = CASE WHEN xp + ? < 0 THEN 0 ELSE xp + ? END WHERE id = ?`, xp, xp, playerID)
+	if err != nil {
+		return 0, err
+	}
+	return r.xpAfter(res, playerID)
+}
+
+// RaiseXP raises the player's experience to at least xp in a single
+// statement and returns the new total.
+func (r *SQLPlayerRepository) RaiseXP(playerID int, xp int64) (int64, error) {
+	res, err := r.db.Exec(`UPDATE players SET xp = CASE WHEN xp < ? THEN ? ELSE xp END WHERE id = ?`, xp, xp, playerID)
+	if err != nil {
+		return 0, err
+	}
+	return r.xpAfter(res, playerID)
+}
+
+// xpAfter returns the player's experience after an update that res reports
+// on.
+func (r *SQLPlayerRepository) xpAfter(res sql.Result, playerID int) (int64, error) {
+	if err := requireAffected(res, ErrPlayerNotFound); err != nil {
+		return 0, err
+	}
+	var total int64
+	err := r.db.QueryRow(`SELECT xp FROM players WHERE id = ?`, playerID).Scan(&total)
+	return total, err
+}
+
+func scanPlayer(row rowScanner) (*models.Player, error) {
+	var (
+		player     models.Player
+		levelID    sql.NullInt64
+		levelName  sql.NullString
+		levelOrder sql.NullInt64
+		levelMinXP sql.NullInt64
+		maxWeekly  sql.NullInt64
+		fee        sql.Null[models.Money]
+		cooldown   sql.NullInt64
+	)
+	if err := row.Scan(&player.ID, &player.Name, &player.Balance, &player.Role, &player.XP,
+		&levelID, &levelName, &levelOrder, &levelMinXP, &maxWeekly, &fee, &cooldown); err != nil {
+		return nil, err
+	}
+	if levelID.Valid {
+		player.Level = &models.Level{
+			ID:    int(levelID.Int64),
+			Name:  levelName.String,
+			Order: int(levelOrder.Int64),
+			MinXP: levelMinXP.Int64,
+			Perks: models.LevelPerks{
+				MaxWeeklyReservations:    int(maxWeekly.Int64),
+				ChallengeCooldownSeconds: int(cooldown.Int64),
+			},
+		}
+		if fee.Valid {
+			player.Level.Perks.ChallengeFee = &fee.V
+		}
+	}
+	return &player, nil
+}
+
+// levelID returns the value stored in players.level_id for level.
+func levelID(level *models.Level) sql.NullInt64 {
+	if level == nil || level.ID == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(level.ID), Valid: true}
+}
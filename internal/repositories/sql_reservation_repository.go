@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+const reservationColumns = `id, room_id, date, time, player_id, status, series_id, created_at`
+
+// SQLReservationRepository stores reservations in the reservations table.
+type SQLReservationRepository struct {
+	db dbtx
+}
+
+// NewSQLReservationRepository creates a new SQLReservationRepository.
+func NewSQLReservationRepository(db *sql.DB) *SQLReservationRepository {
+	return &SQLReservationRepository{db: db}
+}
+
+func (r *SQLReservationRepository) Create(reservation *models.Reservation) (int, error) {
+	// TIMESTAMP columns only keep whole seconds
+	reservation.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.db.Exec(`INSERT INTO reservations (room_id, date, time, player_id, status, series_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		reservation.RoomID, reservation.Date, reservation.Time, reservation.PlayerID, reservation.Status,
+		seriesID(reservation.SeriesID), reservation.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	reservation.ID = int(id)
+	return reservation.ID, nil
+}
+
+func (r *SQLReservationRepository) GetById(id int) (*models.Reservation, error) {
+	row := r.db.QueryRow(`SELECT `+reservationColumns+` FROM reservations WHERE id = ?`, id)
+	reservation, err := scanReservation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, err
+}
+
+func (r *SQLReservationRepository) List() []*models.Reservation {
+	return r.query(`SELECT ` + reservationColumns + ` FROM reservations ORDER BY id`)
+}
+
+func (r *SQLReservationRepository) ListByRoom(roomID int) []*models.Reservation {
+	return r.query(`SELECT `+reservationColumns+` FROM reservations WHERE room_id = ? ORDER BY id`, roomID)
+}
+
+func (r *SQLReservationRepository) ListByRoomAndDate(roomID int, date time.Time) []*models.Reservation {
+	return r.query(`SELECT `+reservationColumns+` FROM reservations WHERE room_id = ? AND date = ? ORDER BY id`,
+		roomID, date)
+}
+
+func (r *SQLReservationRepository) ListByPlayer(playerID int, from, to time.Time) []*models.Reservation {
+	return r.query(`SELECT `+reservationColumns+` FROM reservations WHERE player_id = ? AND date >= ? AND date < ? ORDER BY date, id`,
+		playerID, from, to)
+}
+
+// ListByStatus returns the reservations in any of statuses, oldest first.
+func (r *SQLReservationRepository) ListByStatus(statuses ...string) []*models.Reservation {
+	if len(statuses) == 0 {
+		return make([]*models.Reservation, 0)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+	return r.query(`SELECT `+reservationColumns+` FROM reservations WHERE status IN (`+placeholders+`) ORDER BY id`, args...)
+}
+
+// Update replaces the room, date, time and status of a reservation.
+func (r *SQLReservationRepository) Update(reservation *models.Reservation) error {
+	res, err := r.db.Exec(`UPDATE reservations SET room_id = ?, date = ?, time = ?, status = ? WHERE id = ?`,
+		reservation.RoomID, reservation.Date, reservation.Time, reservation.Status, reservation.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrReservationNotFound)
+}
+
+func (r *SQLReservationRepository) Delete(id int) error {
+	res, err := r.db.Exec(`DELETE FROM reservations WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrReservationNotFound)
+}
+
+func (r *SQLReservationRepository) query(query string, args ...any) []*models.Reservation {
+	reservations := make([]*models.Reservation, 0)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return reservations
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return reservations
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations
+}
+
+func (r *SQLReservationRepository) CreateSeries(series *models.ReservationSeries) (int, error) {
+	series.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.db.Exec(`INSERT INTO reservation_series (room_id, player_id, time, start_date, rule, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		series.RoomID, series.PlayerID, series.Time, series.StartDate, series.Rule, series.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	series.ID = int(id)
+	return series.ID, nil
+}
+
+func (r *SQLReservationRepository) GetSeriesByID(id int) (*models.ReservationSeries, error) {
+	var series models.ReservationSeries
+	err := r.db.QueryRow(`SELECT id, room_id, player_id, time, start_date, rule, created_at FROM reservation_series WHERE id = ?`, id).
+		Scan(&series.ID, &series.RoomID, &series.PlayerID, &series.Time, &series.StartDate, &series.Rule, &series.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReservationSeriesNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *SQLReservationRepository) ListBySeries(seriesID int) []*models.Reservation {
+	return r.query(`SELECT `+reservationColumns+` FROM reservations WHERE series_id = ? ORDER BY date, id`, seriesID)
+}
+
+func scanReservation(row rowScanner) (*models.Reservation, error) {
+	var (
+		reservation models.Reservation
+		seriesID    sql.NullInt64
+	)
+	err := row.Scan(&reservation.ID, &reservation.RoomID, &reservation.Date, &reservation.Time,
+		&reservation.PlayerID, &reservation.Status, &seriesID, &reservation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	reservation.SeriesID = int(seriesID.Int64)
+	return &reservation, nil
+}
+
+// seriesID returns the value stored in reservations.series_id for id.
+func seriesID(id int) sql.NullInt64 {
+	if id == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(id), Valid: true}
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+const roomColumns = `id, name, description, status, capacity, slot_minutes, opens_at, closes_at`
+
+// SQLRoomRepository stores rooms in the rooms table.
+type SQLRoomRepository struct {
+	db dbtx
+}
+
+// NewSQLRoomRepository creates a new SQLRoomRepository.
+func NewSQLRoomRepository(db *sql.DB) *SQLRoomRepository {
+	return &SQLRoomRepository{db: db}
+}
+
+// GetAllRooms returns all rooms.
+func (r *SQLRoomRepository) GetAllRooms() ([]models.Room, error) {
+	rows, err := r.db.Query(`SELECT ` + roomColumns + ` FROM rooms ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := make([]models.Room, 0)
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, *room)
+	}
+	return rooms, rows.Err()
+}
+
+// GetRoomByID returns the room with the given ID.
+func (r *SQLRoomRepository) GetRoomByID(id int) (*models.Room, error) {
+	row := r.db.QueryRow(`SELECT `+roomColumns+` FROM rooms WHERE id = ?`, id)
+	room, err := scanRoom(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoomNotFound
+	}
+	return room, err
+}
+
+// CreateRoom adds a new room and returns the new room's ID.
+func (r *SQLRoomRepository) CreateRoom(room models.Room) (int, error) {
+	res, err := r.db.Exec(`INSERT INTO rooms (name, description, status, capacity, slot_minutes, opens_at, closes_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		room.Name, room.Description, room.Status, room.Capacity, room.SlotMinutes, room.OpensAt, room.ClosesAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdateRoom updates the room with the given ID.
+func (r *SQLRoomRepository) UpdateRoom(id int, updatedRoom models.Room) error {
+	res, err := r.db.Exec(`UPDATE rooms SET name = ?, description = ?, status = ?, capacity = ?, slot_minutes = ?, opens_at = ?, closes_at = ? WHERE id = ?`,
+		updatedRoom.Name, updatedRoom.Description, updatedRoom.Status, updatedRoom.Capacity, updatedRoom.SlotMinutes,
+		updatedRoom.OpensAt, updatedRoom.ClosesAt, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrRoomNotFound)
+}
+
+// DeleteRoom deletes the room with the given ID.
+func (r *SQLRoomRepository) DeleteRoom(id int) error {
+	res, err := r.db.Exec(`DELETE FROM rooms WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrRoomNotFound)
+}
+
+// AddOccupant records a player entering a room. EnteredAt is stored to the
+// second.
+func (r *SQLRoomRepository) AddOccupant(occupant models.RoomOccupant) error {
+	if _, err := r.GetOccupant(occupant.PlayerID); err == nil {
+		return ErrPlayerAlreadyInRoom
+	} else if !errors.Is(err, ErrOccupantNotFound) {
+		return err
+	}
+	_, err := r.db.Exec(`INSERT INTO room_occupants (player_id, room_id, entered_at) VALUES (?, ?, ?)`,
+		occupant.PlayerID, occupant.RoomID, occupant.EnteredAt.UTC().Truncate(time.Second))
+	return err
+}
+
+// RemoveOccupant records a player leaving their room.
+func (r *SQLRoomRepository) RemoveOccupant(playerID int) error {
+	res, err := r.db.Exec(`DELETE FROM room_occupants WHERE player_id = ?`, playerID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ErrOccupantNotFound)
+}
+
+// GetOccupant returns the room a player is in.
+func (r *SQLRoomRepository) GetOccupant(playerID int) (*models.RoomOccupant, error) {
+	var occupant models.RoomOccupant
+	err := r.db.QueryRow(`SELECT room_id, player_id, entered_at FROM room_occupants WHERE player_id = ?`, playerID).
+		Scan(&occupant.RoomID, &occupant.PlayerID, &occupant.EnteredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOccupantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &occupant, nil
+}
+
+// ListOccupants returns the players in a room, in the order they entered.
+func (r *SQLRoomRepository) ListOccupants(roomID int) ([]models.RoomOccupant, error) {
+	rows, err := r.db.Query(`SELECT room_id, player_id, entered_at FROM room_occupants WHERE room_id = ? ORDER BY entered_at, player_id`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occupants := make([]models.RoomOccupant, 0)
+	for rows.Next() {
+		var occupant models.RoomOccupant
+		if err := rows.Scan(&occupant.RoomID, &occupant.PlayerID, &occupant.EnteredAt); err != nil {
+			return nil, err
+		}
+		occupants = append(occupants, occupant)
+	}
+	return occupants, rows.Err()
+}
+
+func scanRoom(row rowScanner) (*models.Room, error) {
+	var (
+		room                models.Room
+		description, status sql.NullString
+	)
+	if err := row.Scan(&room.ID, &room.Name, &description, &status, &room.Capacity, &room.SlotMinutes,
+		&room.OpensAt, &room.ClosesAt); err != nil {
+		return nil, err
+	}
+	room.Description = description.String
+	room.Status = status.String
+	return &room, nil
+}
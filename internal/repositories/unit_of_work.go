@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// Repositories groups the repositories that take part in a unit of work.
+type Repositories struct {
+	Players      PlayerRepository
+	Levels       LevelRepository
+	Challenges   ChallengeRepository
+	Jackpot      JackpotRepository
+	Payments     PaymentRepository
+	Ledger       LedgerRepository
+	Auth         AuthRepository
+	Reservations ReservationRepository
+}
+
+// UnitOfWork runs a group of repository changes atomically.
+type UnitOfWork interface {
+	// Do calls fn with repositories whose changes are committed together when
+	// fn returns nil and rolled back when it returns an error.
+	Do(fn func(repos Repositories) error) error
+}
+
+// undoJournal records how to reverse each write made through a unit of
+// work's repositories, so a rollback undoes only those writes. A nil journal
+// records nothing.
+type undoJournal struct {
+	mu    sync.Mutex
+	undos []func()
+}
+
+// record adds undo, which must take the repository's lock itself.
+func (j *undoJournal) record(undo func()) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.undos = append(j.undos, undo)
+}
+
+// rollback undoes the recorded writes, newest first.
+func (j *undoJournal) rollback() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := len(j.undos) - 1; i >= 0; i-- {
+		j.undos[i]()
+	}
+	j.undos = nil
+}
+
+// journaled returns repo with its writes recorded in j, for the in-memory
+// repositories that support it.
+func journaled[R any](repo R, j *undoJournal) R {
+	if r, ok := any(repo).(interface{ withJournal(*undoJournal) R }); ok {
+		return r.withJournal(j)
+	}
+	return repo
+}
+
+// InMemoryUnitOfWork makes changes to in-memory repositories atomic by
+// recording how to undo each write made within fn and undoing them if it
+// fails. Units of work run one at a time; writes made outside a unit of work
+// are left alone by its rollback.
+type InMemoryUnitOfWork struct {
+	mu    sync.Mutex
+	repos Repositories
+}
+
+// NewInMemoryUnitOfWork creates a unit of work over the given in-memory
+// repositories.
+func NewInMemoryUnitOfWork(repos Repositories) *InMemoryUnitOfWork {
+	return &InMemoryUnitOfWork{repos: repos}
+}
+
+func (u *InMemoryUnitOfWork) Do(fn func(repos Repositories) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	journal := &undoJournal{}
+	repos := Repositories{
+		Players:      journaled(u.repos.Players, journal),
+		Levels:       journaled(u.repos.Levels, journal),
+		Challenges:   journaled(u.repos.Challenges, journal),
+		Jackpot:      journaled(u.repos.Jackpot, journal),
+		Payments:     journaled(u.repos.Payments, journal),
+		Ledger:       journaled(u.repos.Ledger, journal),
+		Auth:         journaled(u.repos.Auth, journal),
+		Reservations: journaled(u.repos.Reservations, journal),
+	}
+	if err := fn(repos); err != nil {
+		journal.rollback()
+		return err
+	}
+	return nil
+}
+
+// SQLUnitOfWork runs each unit of work in a database transaction.
+type SQLUnitOfWork struct {
+	db *sql.DB
+}
+
+// NewSQLUnitOfWork creates a SQLUnitOfWork.
+func NewSQLUnitOfWork(db *sql.DB) *SQLUnitOfWork {
+	return &SQLUnitOfWork{db: db}
+}
+
+func (u *SQLUnitOfWork) Do(fn func(repos Repositories) error) error {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	repos := Repositories{
+		Players:      &SQLPlayerRepository{db: tx},
+		Levels:       &SQLLevelRepository{db: tx},
+		Challenges:   &SQLChallengeRepository{db: tx},
+		Jackpot:      &SQLJackpotRepository{db: tx},
+		Payments:     &SQLPaymentRepository{db: tx},
+		Ledger:       &SQLLedgerRepository{db: tx},
+		Auth:         &SQLAuthRepository{db: tx},
+		Reservations: &SQLReservationRepository{db: tx},
+	}
+	if err := fn(repos); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
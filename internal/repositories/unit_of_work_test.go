@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"oxo_game/internal/models"
+)
+
+// unitOfWorkBackend is a unit of work together with the repositories it
+// changes, as seen from outside the unit of work.
+type unitOfWorkBackend struct {
+	uow   UnitOfWork
+	repos Repositories
+}
+
+func unitsOfWork(t *testing.T, test func(t *testing.T, uow UnitOfWork, repos Repositories)) {
+	forEachBackend(t,
+		func() unitOfWorkBackend {
+			repos := Repositories{
+				Players:    NewInMemoryPlayerRepository(),
+				Challenges: NewInMemoryChallengeRepository(),
+			}
+			return unitOfWorkBackend{NewInMemoryUnitOfWork(repos), repos}
+		},
+		func(db *sql.DB) unitOfWorkBackend {
+			repos := Repositories{
+				Players:    NewSQLPlayerRepository(db),
+				Challenges: NewSQLChallengeRepository(db),
+			}
+			return unitOfWorkBackend{NewSQLUnitOfWork(db), repos}
+		},
+		func(t *testing.T, b unitOfWorkBackend) { test(t, b.uow, b.repos) })
+}
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	unitsOfWork(t, func(t *testing.T, uow UnitOfWork, repos Repositories) {
+		playerID, err := repos.Players.CreatePlayer(models.Player{Name: "Alice", Balance: models.Cents(100_00)})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		// Charge the player and record a challenge together
+		err = uow.Do(func(tx Repositories) error {
+			if err := tx.Players.DeductBalance(playerID, models.Cents(20_00)); err != nil {
+				return err
+			}
+			if err := tx.Players.CreditBalance(playerID, models.Cents(5_00)); err != nil {
+				return err
+			}
+			_, err := tx.Challenges.Create(&models.Challenge{PlayerID: playerID})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Error running unit of work: %v", err)
+		}
+
+		player, err := repos.Players.GetPlayerByID(playerID)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if player.Balance != models.Cents(85_00) {
+			t.Errorf("Expected balance 85.00 after commit, got %s", player.Balance)
+		}
+		if n := len(repos.Challenges.ListByPlayer(playerID)); n != 1 {
+			t.Errorf("Expected 1 challenge after commit, got %d", n)
+		}
+	})
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	unitsOfWork(t, func(t *testing.T, uow UnitOfWork, repos Repositories) {
+		playerID, err := repos.Players.CreatePlayer(models.Player{Name: "Bob", Balance: models.Cents(100_00)})
+		if err != nil {
+			t.Fatalf("Error creating player: %v", err)
+		}
+
+		// Fail after the player has been charged and the challenge recorded
+		errBoom := errors.New("boom")
+		err = uow.Do(func(tx Repositories) error {
+			if err := tx.Players.DeductBalance(playerID, models.Cents(20_00)); err != nil {
+				return err
+			}
+			if _, err := tx.Challenges.Create(&models.Challenge{PlayerID: playerID}); err != nil {
+				return err
+			}
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("Expected the unit of work to return errBoom, got %v", err)
+		}
+
+		player, err := repos.Players.GetPlayerByID(playerID)
+		if err != nil {
+			t.Fatalf("Error fetching player: %v", err)
+		}
+		if player.Balance != models.Cents(100_00) {
+			t.Errorf("Expected balance 100.00 after rollback, got %s", player.Balance)
+		}
+		if n := len(repos.Challenges.ListByPlayer(playerID)); n != 0 {
+			t.Errorf("Expected no challenges after rollback, got %d", n)
+		}
+	})
+}
+
+func TestInMemoryUnitOfWork_RollbackKeepsOutsideWrites(t *testing.T) {
+	players := NewInMemoryPlayerRepository()
+	auth := NewInMemoryAuthRepository()
+	uow := NewInMemoryUnitOfWork(Repositories{Players: players, Auth: auth})
+
+	playerID, err := players.CreatePlayer(models.Player{Name: "Carol", Balance: models.Cents(100_00)})
+	if err != nil {
+		t.Fatalf("Error creating player: %v", err)
+	}
+
+	// Write outside the unit of work while it runs, then fail it
+	var outsiderID int
+	errBoom := errors.New("boom")
+	err = uow.Do(func(tx Repositories) error {
+		if err := tx.Players.DeductBalance(playerID, models.Cents(20_00)); err != nil {
+			return err
+		}
+		if err := auth.CreateSession(&models.Session{TokenHash: "outside", PlayerID: playerID}); err != nil {
+			return err
+		}
+		if outsiderID, err = players.CreatePlayer(models.Player{Name: "Dave"}); err != nil {
+			return err
+		}
+		if err := players.CreditBalance(playerID, models.Cents(5_00)); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expected the unit of work to return errBoom, got %v", err)
+	}
+
+	player, err := players.GetPlayerByID(playerID)
+	if err != nil {
+		t.Fatalf("Error fetching player: %v", err)
+	}
+	if player.Balance != models.Cents(105_00) {
+		t.Errorf("Expected only the charge to be undone leaving 105.00, got %s", player.Balance)
+	}
+	if _, err := players.GetPlayerByID(outsiderID); err != nil {
+		t.Errorf("Expected the player created outside the unit of work to remain, got %v", err)
+	}
+	if _, err := auth.GetSession("outside"); err != nil {
+		t.Errorf("Expected the session created outside the unit of work to remain, got %v", err)
+	}
+}
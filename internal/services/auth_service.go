@@ -0,0 +1,252 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+// DefaultSessionTTL is how long a session lasts when no TTL is configured.
+const DefaultSessionTTL = 24 * time.Hour
+
+// Passwords are bcrypt hashed, which only looks at the first 72 bytes.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+var (
+	ErrInvalidUsername    = errors.New("username must be 3 to 64 letters, digits, '.', '-' or '_'")
+	ErrWeakPassword       = fmt.Errorf("password must be %d to %d bytes long", minPasswordLength, maxPasswordLength)
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired session token")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,64}$`)
+
+// dummyPasswordHash is compared against when a username is unknown, so that
+// a failed login takes as long whether or not the username exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// LoginResult is a new session. Token is only ever returned here; it is sent
+// back as "Authorization: Bearer <token>".
+type LoginResult struct {
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Player    *models.Player `json:"player"`
+}
+
+type AuthService interface {
+	// Register creates a player with a zero balance and the player role,
+	// together with the credentials they log in with. The player's name
+	// defaults to the username, which is stored in lower case.
+	Register(username, password string, player models.Player) (*models.Player, error)
+	// BootstrapAdmin makes sure username is an admin, registering it with
+	// password if it does not exist yet. An existing password is kept.
+	BootstrapAdmin(username, password string) (*models.Player, error)
+	Login(username, password string) (*LoginResult, error)
+	// Logout ends the session of token.
+	Logout(token string) error
+	// Authenticate returns the player whose session token is token.
+	Authenticate(token string) (*models.Player, error)
+	// SweepSessions deletes the sessions that expired by now.
+	SweepSessions(now time.Time) (int, error)
+}
+
+type authService struct {
+	authRepo   repositories.AuthRepository
+	playerRepo repositories.PlayerRepository
+	uow        repositories.UnitOfWork
+	events     EventPublisher
+	sessionTTL time.Duration
+}
+
+func NewAuthService(authRepo repositories.AuthRepository, playerRepo repositories.PlayerRepository, uow repositories.UnitOfWork, events EventPublisher, sessionTTL time.Duration) AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = DefaultSessionTTL
+	}
+	return &authService{
+		authRepo:   authRepo,
+		playerRepo: playerRepo,
+		uow:        uow,
+		events:     events,
+		sessionTTL: sessionTTL,
+	}
+}
+
+func (s *authService) Register(username, password string, player models.Player) (*models.Player, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if player.Name == "" {
+		player.Name = username
+	}
+	player.Balance = models.Money{}
+	player.Role = models.RolePlayer
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if player.ID, err = repos.Players.CreatePlayer(player); err != nil {
+			return err
+		}
+		return repos.Auth.CreateCredentials(&models.Credentials{
+			PlayerID:     player.ID,
+			Username:     username,
+			PasswordHash: string(hash),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuth(player.ID, models.LogActionRegister, models.LogDetails{"username": username, "role": string(player.Role)})
+	return &player, nil
+}
+
+func (s *authService) BootstrapAdmin(username, password string) (*models.Player, error) {
+	credentials, err := s.authRepo.GetCredentialsByUsername(strings.ToLower(strings.TrimSpace(username)))
+	if errors.Is(err, repositories.ErrCredentialsNotFound) {
+		player, err := s.Register(username, password, models.Player{})
+		if err != nil {
+			return nil, err
+		}
+		credentials = &models.Credentials{PlayerID: player.ID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var player *models.Player
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if player, err = repos.Players.GetPlayerByID(credentials.PlayerID); err != nil {
+			return err
+		}
+		player.Role = models.RoleAdmin
+		return repos.Players.UpdatePlayer(player.ID, *player)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+func (s *authService) Login(username, password string) (*LoginResult, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	credentials, err := s.authRepo.GetCredentialsByUsername(username)
+	if errors.Is(err, repositories.ErrCredentialsNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	player, err := s.playerRepo.GetPlayerByID(credentials.PlayerID)
+	if errors.Is(err, repositories.ErrPlayerNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &models.Session{
+		TokenHash: hashSessionToken(token),
+		PlayerID:  player.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.authRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	s.logAuth(player.ID, models.LogActionLogin, nil)
+	return &LoginResult{Token: token, ExpiresAt: session.ExpiresAt, Player: player}, nil
+}
+
+func (s *authService) Logout(token string) error {
+	session, err := s.authRepo.GetSession(hashSessionToken(token))
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.authRepo.DeleteSession(session.TokenHash); err != nil && !errors.Is(err, repositories.ErrSessionNotFound) {
+		return err
+	}
+
+	s.logAuth(session.PlayerID, models.LogActionLogout, nil)
+	return nil
+}
+
+func (s *authService) Authenticate(token string) (*models.Player, error) {
+	session, err := s.authRepo.GetSession(hashSessionToken(token))
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Expired(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	player, err := s.playerRepo.GetPlayerByID(session.PlayerID)
+	if errors.Is(err, repositories.ErrPlayerNotFound) {
+		return nil, ErrInvalidToken
+	}
+	return player, err
+}
+
+func (s *authService) SweepSessions(now time.Time) (int, error) {
+	return s.authRepo.DeleteExpiredSessions(now)
+}
+
+// newSessionToken returns a random, URL-safe session token.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSessionToken returns the hex SHA-256 of token, which is what sessions
+// are stored under.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *authService) logAuth(playerID int, action models.LogAction, details models.LogDetails) {
+	s.events.Publish(Event{
+		Action:   action,
+		PlayerID: playerID,
+		Details:  details,
+	})
+}
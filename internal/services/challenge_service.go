@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -10,83 +11,300 @@ import (
 	"oxo_game/internal/repositories"
 )
 
-const (
-	challengeDuration     = 30 * time.Second
-	challengeCooldownTime = 1 * time.Minute
-	jackpotWinProbability = 1 // 1% chance to win jackpot
+// DefaultChallengeDefinition is the challenge the game started with. It is
+// created when there are no challenges at all.
+var DefaultChallengeDefinition = models.ChallengeDefinition{
+	Name:            "Endless Challenge",
+	Fee:             models.MustParseMoney("20.01"),
+	WinProbability:  0.01,
+	DurationSeconds: 30,
+	CooldownSeconds: 30,
+}
+
+var (
+	ErrPlayerOnCooldown            = errors.New("player is on cooldown")
+	ErrChallengeLimitReached       = errors.New("player has no attempts left at this challenge today")
+	ErrChallengeInactive           = errors.New("challenge is not open")
+	ErrLevelTooLow                 = errors.New("player's level is too low for this challenge")
+	ErrInvalidChallengeDefinition  = errors.New("invalid challenge definition")
+	ErrChallengeDefinitionNotFound = repositories.ErrChallengeDefinitionNotFound
 )
 
+// ChallengeOutcome is the result of taking part in a challenge.
+type ChallengeOutcome struct {
+	ChallengeID   int          `json:"challenge_id"`
+	DefinitionID  int          `json:"definition_id"`
+	Fee           models.Money `json:"fee"`
+	EndsAt        time.Time    `json:"ends_at"`
+	WonJackpot    bool         `json:"won_jackpot"`
+	JackpotPayout models.Money `json:"jackpot_payout"`
+}
+
 type ChallengeService interface {
-	ParticipateChallenge(playerID int) (bool, error)
+	// ParticipateChallenge enters the player into the first challenge that
+	// is open.
+	ParticipateChallenge(playerID int) (*ChallengeOutcome, error)
+	// JoinChallenge enters the player into the challenge definitionID.
+	JoinChallenge(playerID, definitionID int) (*ChallengeOutcome, error)
 	ListLatestChallenges(n int) []*models.Challenge
+
+	ListDefinitions() []*models.ChallengeDefinition
+	GetDefinition(id int) (*models.ChallengeDefinition, error)
+	CreateDefinition(definition models.ChallengeDefinition) (*models.ChallengeDefinition, error)
+	UpdateDefinition(id int, definition models.ChallengeDefinition) (*models.ChallengeDefinition, error)
+	// DeleteDefinition deletes a challenge. Past attempts at it are kept.
+	DeleteDefinition(id int) error
 }
 
 type challengeService struct {
-	challengeRepo repositories.ChallengeRepository
-	playerRepo    repositories.PlayerRepository // Assuming you have a player repository
-	mu            sync.Mutex
+	challengeRepo  repositories.ChallengeRepository
+	definitionRepo repositories.ChallengeDefinitionRepository
+	playerRepo     repositories.PlayerRepository
+	levelRepo      repositories.LevelRepository
+	jackpotService JackpotService
+	uow            repositories.UnitOfWork
+	events         EventPublisher
+	mu             sync.Mutex
 }
 
-func NewChallengeService(challengeRepo repositories.ChallengeRepository, playerRepo repositories.PlayerRepository) ChallengeService {
+// NewChallengeService creates a ChallengeService, and the default challenge
+// if there are no challenges yet.
+func NewChallengeService(challengeRepo repositories.ChallengeRepository, definitionRepo repositories.ChallengeDefinitionRepository, playerRepo repositories.PlayerRepository, levelRepo repositories.LevelRepository, jackpotService JackpotService, uow repositories.UnitOfWork, events EventPublisher) (ChallengeService, error) {
+	if len(definitionRepo.List()) == 0 {
+		definition := DefaultChallengeDefinition
+		if _, err := definitionRepo.Create(&definition); err != nil {
+			return nil, err
+		}
+	}
 	return &challengeService{
-		challengeRepo: challengeRepo,
-		playerRepo:    playerRepo,
+		challengeRepo:  challengeRepo,
+		definitionRepo: definitionRepo,
+		playerRepo:     playerRepo,
+		levelRepo:      levelRepo,
+		jackpotService: jackpotService,
+		uow:            uow,
+		events:         events,
+	}, nil
+}
+
+func (s *challengeService) ParticipateChallenge(playerID int) (*ChallengeOutcome, error) {
+	now := time.Now()
+	for _, definition := range s.definitionRepo.List() {
+		if definition.ActiveAt(now) {
+			return s.JoinChallenge(playerID, definition.ID)
+		}
 	}
+	return nil, fmt.Errorf("%w: no challenge is open", ErrChallengeInactive)
 }
 
-func (s *challengeService) ParticipateChallenge(playerID int) (bool, error) {
+// JoinChallenge checks that the player may enter the challenge, then charges
+// the entry fee, feeds the jackpot pool, records the outcome and pays out a
+// won jackpot in one unit of work, so a player is never charged for a
+// challenge that was not recorded.
+func (s *challengeService) JoinChallenge(playerID, definitionID int) (*ChallengeOutcome, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Check if the player is eligible to participate
-	lastChallenge, err := s.getLastChallengeForPlayer(playerID)
-	if err != nil && err.Error() != repositories.ErrChallengeNotFound.Error() {
-		return false, err
+	definition, err := s.definitionRepo.GetById(definitionID)
+	if err != nil {
+		return nil, err
 	}
-	if lastChallenge != nil {
-		if time.Since(lastChallenge.CreatedAt) < challengeCooldownTime {
-			return false, errors.New("player is on cooldown")
-		}
+	now := time.Now()
+	if !definition.ActiveAt(now) {
+		return nil, fmt.Errorf("%w: %s", ErrChallengeInactive, definition.Name)
+	}
+
+	// Turn ineligible players away before any writes; joins are serialized
+	// by mu, so nothing changes between the checks and the unit of work
+	fee, err := s.checkEligibility(playerID, definition, now)
+	if err != nil {
+		return nil, err
 	}
 
-	// Deduct payment from the player (assuming this operation is successful)
-	err = s.playerRepo.DeductBalance(playerID, 20.01)
+	outcome := &ChallengeOutcome{DefinitionID: definition.ID, Fee: fee}
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		// Deduct payment from the player
+		if err := repos.Players.DeductBalance(playerID, fee); err != nil {
+			return err
+		}
+		if err := recordWalletPayment(repos, playerID, models.PaymentTypeChallengeFee, fee.Neg()); err != nil {
+			return err
+		}
+		jackpotShare, err := s.jackpotService.Contribute(repos, fee)
+		if err != nil {
+			return err
+		}
+
+		// Simulate the challenge
+		outcome.WonJackpot = rand.Float64() < definition.WinProbability
+
+		// Create a new challenge record
+		challenge := &models.Challenge{
+			PlayerID:     playerID,
+			DefinitionID: definition.ID,
+			CreatedAt:    now,
+			Won:          outcome.WonJackpot,
+		}
+		outcome.ChallengeID, err = repos.Challenges.Create(challenge)
+		if err != nil {
+			return err
+		}
+		outcome.EndsAt = challenge.CreatedAt.Add(definition.Duration())
+		_, err = postLedger(repos, models.LedgerKindChallengeFee, "", fmt.Sprintf("challenge:%d", outcome.ChallengeID),
+			models.LedgerEntry{Account: models.PlayerAccount(playerID), Amount: fee.Neg()},
+			models.LedgerEntry{Account: models.AccountJackpot, Amount: jackpotShare},
+			models.LedgerEntry{Account: models.AccountRevenue, Amount: fee.Sub(jackpotShare)})
+		if err != nil {
+			return err
+		}
+
+		if outcome.WonJackpot {
+			outcome.JackpotPayout, err = s.jackpotService.PayOut(repos, playerID, outcome.ChallengeID)
+		}
+		return err
+	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// Simulate the challenge
-	wonJackpot := s.simulateChallenge()
+	s.events.Publish(Event{
+		Action:   models.LogActionParticipateChallenge,
+		PlayerID: playerID,
+		Details: models.LogDetails{
+			"challenge_id":  outcome.ChallengeID,
+			"definition_id": definition.ID,
+			"fee":           outcome.Fee,
+		},
+	})
+	result := models.LogDetails{"challenge_id": outcome.ChallengeID, "definition_id": definition.ID, "won": outcome.WonJackpot}
+	if outcome.WonJackpot {
+		result["jackpot"] = outcome.JackpotPayout
+	}
+	s.events.Publish(Event{
+		Action:   models.LogActionChallengeResult,
+		PlayerID: playerID,
+		Details:  result,
+	})
+	return outcome, nil
+}
 
-	// Create a new challenge record
-	challenge := &models.Challenge{
-		PlayerID:  playerID,
-		CreatedAt: time.Now(),
-		Won:       wonJackpot,
+// checkEligibility returns the fee the player pays to enter the challenge at
+// now, or an error if they may not enter it. The player must be at the
+// challenge's minimum level; their level may cap the fee and change the
+// cooldown.
+func (s *challengeService) checkEligibility(playerID int, definition *models.ChallengeDefinition, now time.Time) (models.Money, error) {
+	player, err := s.playerRepo.GetPlayerByID(playerID)
+	if err != nil {
+		return models.Money{}, err
 	}
-	_, err = s.challengeRepo.Create(challenge)
+	level, err := playerLevel(s.levelRepo, player)
 	if err != nil {
-		return false, err
+		return models.Money{}, err
+	}
+	var perks models.LevelPerks
+	order := 0
+	if level != nil {
+		perks, order = level.Perks, level.Order
+	}
+	if order < definition.MinLevelOrder {
+		return models.Money{}, fmt.Errorf("%w: needs level order %d", ErrLevelTooLow, definition.MinLevelOrder)
+	}
+	fee, cooldown := definition.Fee, definition.Cooldown()
+	if perks.ChallengeFee != nil && perks.ChallengeFee.Cmp(fee) < 0 {
+		fee = *perks.ChallengeFee
+	}
+	if perks.ChallengeCooldownSeconds > 0 {
+		cooldown = time.Duration(perks.ChallengeCooldownSeconds) * time.Second
 	}
 
-	return wonJackpot, nil
+	if err := checkAttempts(s.challengeRepo, definition, playerID, cooldown, now); err != nil {
+		return models.Money{}, err
+	}
+	if player.Balance.Cmp(fee) < 0 {
+		return models.Money{}, repositories.ErrInsufficientBalance
+	}
+	return fee, nil
 }
 
 func (s *challengeService) ListLatestChallenges(n int) []*models.Challenge {
 	return s.challengeRepo.ListLatest(n)
 }
 
-func (s *challengeService) getLastChallengeForPlayer(playerID int) (*models.Challenge, error) {
-	challenges := s.challengeRepo.ListByPlayer(playerID)
-	if len(challenges) == 0 {
-		return nil, repositories.ErrChallengeNotFound
+func (s *challengeService) ListDefinitions() []*models.ChallengeDefinition {
+	return s.definitionRepo.List()
+}
+
+func (s *challengeService) GetDefinition(id int) (*models.ChallengeDefinition, error) {
+	return s.definitionRepo.GetById(id)
+}
+
+func (s *challengeService) CreateDefinition(definition models.ChallengeDefinition) (*models.ChallengeDefinition, error) {
+	if err := validateChallengeDefinition(definition); err != nil {
+		return nil, err
+	}
+	if _, err := s.definitionRepo.Create(&definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+func (s *challengeService) UpdateDefinition(id int, definition models.ChallengeDefinition) (*models.ChallengeDefinition, error) {
+	if err := validateChallengeDefinition(definition); err != nil {
+		return nil, err
 	}
-	return challenges[len(challenges)-1], nil
+	definition.ID = id
+	if err := s.definitionRepo.Update(&definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+func (s *challengeService) DeleteDefinition(id int) error {
+	return s.definitionRepo.Delete(id)
+}
+
+// checkAttempts returns an error unless the player may attempt the challenge
+// at now: their last attempt at it must have ended cooldown ago, and they
+// must have attempts left today.
+func checkAttempts(challengeRepo repositories.ChallengeRepository, definition *models.ChallengeDefinition, playerID int, cooldown time.Duration, now time.Time) error {
+	var last *models.Challenge
+	today := 0
+	midnight := utcDate(now)
+	for _, challenge := range challengeRepo.ListByPlayer(playerID) {
+		if challenge.DefinitionID != definition.ID {
+			continue
+		}
+		if last == nil || challenge.CreatedAt.After(last.CreatedAt) {
+			last = challenge
+		}
+		if !challenge.CreatedAt.Before(midnight) {
+			today++
+		}
+	}
+
+	if last != nil {
+		if next := last.CreatedAt.Add(definition.Duration() + cooldown); now.Before(next) {
+			return fmt.Errorf("%w: next attempt at %s", ErrPlayerOnCooldown, next.UTC().Format(time.RFC3339))
+		}
+	}
+	if definition.MaxPerDay > 0 && today >= definition.MaxPerDay {
+		return fmt.Errorf("%w: at most %d a day", ErrChallengeLimitReached, definition.MaxPerDay)
+	}
+	return nil
 }
 
-func (s *challengeService) simulateChallenge() bool {
-	// Simulate a 1% chance to win the jackpot
-	rand.Seed(time.Now().UnixNano())
-	chance := rand.Intn(100)
-	return chance == 0
+func validateChallengeDefinition(definition models.ChallengeDefinition) error {
+	switch {
+	case definition.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidChallengeDefinition)
+	case !definition.Fee.IsPositive():
+		return fmt.Errorf("%w: fee must be positive", ErrInvalidChallengeDefinition)
+	case definition.WinProbability < 0 || definition.WinProbability > 1:
+		return fmt.Errorf("%w: win_probability must be between 0 and 1", ErrInvalidChallengeDefinition)
+	case definition.DurationSeconds < 0 || definition.CooldownSeconds < 0 || definition.MaxPerDay < 0 || definition.MinLevelOrder < 0:
+		return fmt.Errorf("%w: duration_seconds, cooldown_seconds, max_per_day and min_level_order must not be negative", ErrInvalidChallengeDefinition)
+	case definition.ActiveFrom != nil && definition.ActiveUntil != nil && !definition.ActiveUntil.After(*definition.ActiveFrom):
+		return fmt.Errorf("%w: active_until must be after active_from", ErrInvalidChallengeDefinition)
+	}
+	return nil
 }
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// Topics events are streamed under.
+const (
+	TopicChallenges   = "challenges"
+	TopicJackpot      = "jackpot"
+	TopicRooms        = "rooms"
+	TopicReservations = "reservations"
+)
+
+// Topics lists every topic.
+var Topics = []string{TopicChallenges, TopicJackpot, TopicRooms, TopicReservations}
+
+// eventTopics maps the actions that are streamed to their topic. Account
+// events are only logged.
+var eventTopics = map[models.LogAction]string{
+	models.LogActionParticipateChallenge:   TopicChallenges,
+	models.LogActionChallengeResult:        TopicChallenges,
+	models.LogActionEnterRoom:              TopicRooms,
+	models.LogActionExitRoom:               TopicRooms,
+	models.LogActionRoomStatusChanged:      TopicRooms,
+	models.LogActionReservationCreated:     TopicReservations,
+	models.LogActionReservationConfirmed:   TopicReservations,
+	models.LogActionReservationRescheduled: TopicReservations,
+	models.LogActionReservationCancelled:   TopicReservations,
+	models.LogActionReservationCompleted:   TopicReservations,
+	models.LogActionCheckIn:                TopicReservations,
+	models.LogActionNoShow:                 TopicReservations,
+}
+
+// subscriptionBuffer is how many events a subscriber can fall behind by
+// before it is dropped.
+const subscriptionBuffer = 64
+
+// StreamEvent is an event as the bus delivers it. IDs increase by one for
+// every streamed event.
+type StreamEvent struct {
+	ID     uint64
+	Topics []string
+	Event
+}
+
+// EventFilter selects the events a subscriber receives. Zero fields do not
+// filter.
+type EventFilter struct {
+	Topics   []string
+	PlayerID int
+}
+
+func (f EventFilter) matches(e StreamEvent) bool {
+	if f.PlayerID != 0 && e.PlayerID != f.PlayerID {
+		return false
+	}
+	if len(f.Topics) == 0 {
+		return true
+	}
+	for _, want := range f.Topics {
+		for _, topic := range e.Topics {
+			if topic == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Subscription receives events from an EventBus. Events is closed when the
+// subscriber falls too far behind or the bus is closed; the subscriber can
+// subscribe again from the last ID it saw.
+type Subscription struct {
+	Events <-chan StreamEvent
+	events chan StreamEvent
+	filter EventFilter
+}
+
+// EventBus is the in-process event bus. It passes every published event to
+// its sinks, such as the game log, and streams the events that have a topic
+// to its subscribers. It keeps the latest events so subscribers can resume
+// after reconnecting.
+type EventBus struct {
+	sinks []EventPublisher
+
+	mu          sync.Mutex
+	lastID      uint64
+	history     []StreamEvent
+	historySize int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewEventBus creates an EventBus that remembers the last historySize
+// streamed events. A size of 0 or less remembers none.
+func NewEventBus(historySize int, sinks ...EventPublisher) *EventBus {
+	return &EventBus{
+		sinks:       sinks,
+		historySize: max(historySize, 0),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// AddSink makes the bus pass every event to sink as well. Sinks must be
+// added before events are published.
+func (b *EventBus) AddSink(sink EventPublisher) {
+	b.sinks = append(b.sinks, sink)
+}
+
+// Publish passes event to the sinks and then to the matching subscribers.
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, sink := range b.sinks {
+		sink.Publish(event)
+	}
+
+	topics := topicsOf(event)
+	if len(topics) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	streamed := StreamEvent{ID: b.lastID, Topics: topics, Event: event}
+	b.history = append(b.history, streamed)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+	for sub := range b.subscribers {
+		if !sub.filter.matches(streamed) {
+			continue
+		}
+		select {
+		case sub.events <- streamed:
+		default:
+			// Never let a slow subscriber hold up the services
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscribe starts streaming the events matching filter. Events after
+// lastID that are still remembered are delivered first; pass 0 to only get
+// new events.
+func (b *EventBus) Subscribe(filter EventFilter, lastID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []StreamEvent
+	if lastID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastID && filter.matches(e) {
+				missed = append(missed, e)
+			}
+		}
+	}
+	events := make(chan StreamEvent, len(missed)+subscriptionBuffer)
+	for _, e := range missed {
+		events <- e
+	}
+	sub := &Subscription{Events: events, events: events, filter: filter}
+	if b.closed {
+		close(events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops streaming to sub.
+func (b *EventBus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		b.drop(sub)
+	}
+}
+
+// Close ends every subscription. Events published afterwards still reach
+// the sinks.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub)
+	}
+}
+
+func (b *EventBus) drop(sub *Subscription) {
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// topicsOf returns the topics event is streamed under. A won challenge is
+// also a jackpot win.
+func topicsOf(event Event) []string {
+	topic, ok := eventTopics[event.Action]
+	if !ok {
+		return nil
+	}
+	topics := []string{topic}
+	if won, _ := event.Details["won"].(bool); won && event.Action == models.LogActionChallengeResult {
+		topics = append(topics, TopicJackpot)
+	}
+	return topics
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"time"
+
+	"oxo_game/internal/models"
+)
+
+// Event is a domain event published by the services, such as a player
+// entering a room or a challenge being decided. Every event is recorded in
+// the game log.
+type Event struct {
+	Action   models.LogAction
+	PlayerID int
+	Details  models.LogDetails
+	Time     time.Time
+}
+
+// EventPublisher receives the events the services publish. Publishing never
+// fails the change that caused the event; a publisher reports its own errors.
+type EventPublisher interface {
+	Publish(event Event)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+// JackpotConfig controls how the jackpot pool is funded.
+type JackpotConfig struct {
+	// ContributionRate is the share of each challenge entry fee, between 0
+	// and 1, that goes into the pool.
+	ContributionRate float64
+	// SeedAmount is the balance the pool starts with and is reset to after
+	// every payout.
+	SeedAmount models.Money
+}
+
+// DefaultJackpotConfig is used when no jackpot settings are configured.
+var DefaultJackpotConfig = JackpotConfig{
+	ContributionRate: 0.5,
+	SeedAmount:       models.Cents(100_00),
+}
+
+var (
+	ErrInvalidJackpotConfig = errors.New("jackpot contribution rate must be between 0 and 1 and the seed amount must not be negative")
+)
+
+type JackpotService interface {
+	GetPool() (*models.JackpotPool, error)
+	ListPayouts(n int) []*models.JackpotPayout
+	// Contribute adds the pool's share of fee to the pool and returns it.
+	Contribute(repos repositories.Repositories, fee models.Money) (models.Money, error)
+	// PayOut credits the whole pool to the winner of challengeID, resets the
+	// pool to the seed amount and returns the amount paid.
+	PayOut(repos repositories.Repositories, playerID, challengeID int) (models.Money, error)
+}
+
+type jackpotService struct {
+	jackpotRepo repositories.JackpotRepository
+	config      JackpotConfig
+}
+
+// NewJackpotService creates a JackpotService, and the pool holding the seed
+// amount if there is none yet.
+func NewJackpotService(jackpotRepo repositories.JackpotRepository, config JackpotConfig) (JackpotService, error) {
+	if config.ContributionRate < 0 || config.ContributionRate > 1 || config.SeedAmount.IsNegative() {
+		return nil, ErrInvalidJackpotConfig
+	}
+	if _, err := jackpotRepo.GetPool(); errors.Is(err, repositories.ErrJackpotPoolNotFound) {
+		// Another instance may create the pool first, which is just as good
+		if err := jackpotRepo.CreatePool(config.SeedAmount); err != nil {
+			if _, getErr := jackpotRepo.GetPool(); getErr != nil {
+				return nil, err
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return &jackpotService{
+		jackpotRepo: jackpotRepo,
+		config:      config,
+	}, nil
+}
+
+func (s *jackpotService) GetPool() (*models.JackpotPool, error) {
+	return s.jackpotRepo.GetPool()
+}
+
+func (s *jackpotService) ListPayouts(n int) []*models.JackpotPayout {
+	return s.jackpotRepo.ListPayouts(n)
+}
+
+func (s *jackpotService) Contribute(repos repositories.Repositories, fee models.Money) (models.Money, error) {
+	share := fee.MulRate(s.config.ContributionRate)
+	if err := repos.Jackpot.AddToPool(share); err != nil {
+		return models.Money{}, err
+	}
+	return share, nil
+}
+
+func (s *jackpotService) PayOut(repos repositories.Repositories, playerID, challengeID int) (models.Money, error) {
+	// The house funds the seed the pool restarts from
+	amount, err := repos.Jackpot.ResetPool(s.config.SeedAmount)
+	if err != nil {
+		return models.Money{}, err
+	}
+	if err := repos.Players.CreditBalance(playerID, amount); err != nil {
+		return models.Money{}, err
+	}
+	if err := recordWalletPayment(repos, playerID, models.PaymentTypeJackpotPayout, amount); err != nil {
+		return models.Money{}, err
+	}
+	payout := &models.JackpotPayout{
+		PlayerID:    playerID,
+		ChallengeID: challengeID,
+		Amount:      amount,
+	}
+	if _, err := repos.Jackpot.CreatePayout(payout); err != nil {
+		return models.Money{}, err
+	}
+	_, err = postLedger(repos, models.LedgerKindJackpotPayout, "", fmt.Sprintf("challenge:%d", challengeID),
+		models.LedgerEntry{Account: models.PlayerAccount(playerID), Amount: amount},
+		models.LedgerEntry{Account: models.AccountJackpot, Amount: amount.Neg()},
+		models.LedgerEntry{Account: models.AccountJackpot, Amount: s.config.SeedAmount},
+		models.LedgerEntry{Account: models.AccountRevenue, Amount: s.config.SeedAmount.Neg()})
+	if err != nil {
+		return models.Money{}, err
+	}
+	return amount, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+var (
+	ErrUnbalancedTransaction = errors.New("ledger transaction does not balance")
+	ErrInvalidAdjustment     = errors.New("adjustments need a non-zero amount, refunds a positive one")
+)
+
+// Reconciliation compares a player's stored balance with their ledger account.
+type Reconciliation struct {
+	PlayerID      int          `json:"player_id"`
+	Balance       models.Money `json:"balance"`
+	LedgerBalance models.Money `json:"ledger_balance"`
+	Difference    models.Money `json:"difference"`
+}
+
+type LedgerService interface {
+	// ListPlayerTransactions returns every ledger transaction that changed
+	// the player's balance, oldest first.
+	ListPlayerTransactions(playerID int) ([]*models.LedgerTransaction, error)
+	// Adjust credits (positive amount) or debits (negative amount) a player's
+	// balance by hand. kind is LedgerKindAdjustment or LedgerKindRefund.
+	Adjust(playerID int, kind string, amount models.Money, memo string) (*models.LedgerTransaction, error)
+	// Reconcile returns the players whose balance differs from their ledger
+	// account.
+	Reconcile() ([]Reconciliation, error)
+}
+
+type ledgerService struct {
+	ledgerRepo repositories.LedgerRepository
+	playerRepo repositories.PlayerRepository
+	uow        repositories.UnitOfWork
+}
+
+func NewLedgerService(ledgerRepo repositories.LedgerRepository, playerRepo repositories.PlayerRepository, uow repositories.UnitOfWork) LedgerService {
+	return &ledgerService{
+		ledgerRepo: ledgerRepo,
+		playerRepo: playerRepo,
+		uow:        uow,
+	}
+}
+
+func (s *ledgerService) ListPlayerTransactions(playerID int) ([]*models.LedgerTransaction, error) {
+	if _, err := s.playerRepo.GetPlayerByID(playerID); err != nil {
+		return nil, err
+	}
+	return s.ledgerRepo.ListByAccount(models.PlayerAccount(playerID))
+}
+
+func (s *ledgerService) Adjust(playerID int, kind string, amount models.Money, memo string) (*models.LedgerTransaction, error) {
+	switch {
+	case kind == models.LedgerKindAdjustment && !amount.IsZero():
+	case kind == models.LedgerKindRefund && amount.IsPositive():
+	default:
+		return nil, ErrInvalidAdjustment
+	}
+
+	var transaction *models.LedgerTransaction
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if amount.IsPositive() {
+			err = repos.Players.CreditBalance(playerID, amount)
+		} else {
+			err = repos.Players.DeductBalance(playerID, amount.Neg())
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordWalletPayment(repos, playerID, kind, amount); err != nil {
+			return err
+		}
+		transaction, err = postLedger(repos, kind, memo, "",
+			models.LedgerEntry{Account: models.PlayerAccount(playerID), Amount: amount},
+			models.LedgerEntry{Account: models.AccountAdjustments, Amount: amount.Neg()})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (s *ledgerService) Reconcile() ([]Reconciliation, error) {
+	players, err := s.playerRepo.GetAllPlayers()
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := make([]Reconciliation, 0)
+	for _, player := range players {
+		ledgerBalance, err := s.ledgerRepo.AccountBalance(models.PlayerAccount(player.ID))
+		if err != nil {
+			return nil, err
+		}
+		if difference := player.Balance.Sub(ledgerBalance); !difference.IsZero() {
+			mismatches = append(mismatches, Reconciliation{
+				PlayerID:      player.ID,
+				Balance:       player.Balance,
+				LedgerBalance: ledgerBalance,
+				Difference:    difference,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// postLedger records a balanced ledger transaction within a unit of work.
+func postLedger(repos repositories.Repositories, kind, memo, reference string, entries ...models.LedgerEntry) (*models.LedgerTransaction, error) {
+	var sum models.Money
+	for _, entry := range entries {
+		sum = sum.Add(entry.Amount)
+	}
+	if !sum.IsZero() {
+		return nil, fmt.Errorf("%w: %s entries sum to %s", ErrUnbalancedTransaction, kind, sum)
+	}
+
+	transaction := &models.LedgerTransaction{
+		Kind:      kind,
+		Memo:      memo,
+		Reference: reference,
+		Entries:   entries,
+	}
+	if _, err := repos.Ledger.Post(transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
@@ -2,43 +2,90 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
+	"slices"
+	"sort"
 	"sync"
 )
 
+var (
+	ErrLevelNameTaken  = errors.New("level with the same name already exists")
+	ErrInvalidLevel    = errors.New("invalid level")
+	ErrLevelOrderTaken = errors.New("another level has the same order")
+	ErrLevelOutOfOrder = errors.New("level thresholds must rise with the level order")
+	ErrLevelNotFound   = repositories.ErrLevelNotFound
+	ErrLevelInUse      = errors.New("level is still used by players")
+)
+
 type LevelService interface {
-	CreateLevel(name string) (int, error)
+	// CreateLevel adds a level to the ladder. A zero Order puts it after
+	// the highest level. Players who have earned experience are moved to
+	// the level it now reaches.
+	CreateLevel(level models.Level) (int, error)
 	GetLevelByID(id int) (*models.Level, error)
+	// GetAllLevels returns the levels sorted by order.
 	GetAllLevels() ([]*models.Level, error)
+	// UpdateLevel changes a level's name, order and threshold. A zero Order
+	// keeps the current one.
+	UpdateLevel(id int, level models.Level) (*models.Level, error)
+	// DeleteLevel deletes a level. If players are at the level, it fails
+	// with ErrLevelInUse unless fallbackID names another level to move them
+	// to.
+	DeleteLevel(id, fallbackID int) error
 }
 
 type levelService struct {
 	levelRepo repositories.LevelRepository
+	roomRepo  repositories.RoomRepository
+	uow       repositories.UnitOfWork
+	players   *PlayerService
 	mu        sync.RWMutex
 }
 
-func NewLevelService(repo repositories.LevelRepository) LevelService {
+func NewLevelService(repo repositories.LevelRepository, roomRepo repositories.RoomRepository, uow repositories.UnitOfWork, players *PlayerService) LevelService {
 	return &levelService{
 		levelRepo: repo,
+		roomRepo:  roomRepo,
+		uow:       uow,
+		players:   players,
 	}
 }
 
-func (s *levelService) CreateLevel(name string) (int, error) {
+func (s *levelService) CreateLevel(level models.Level) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.validateLevel(&level); err != nil {
+		return 0, err
+	}
+
 	// Check if level with the same name already exists
 	allLevels := s.levelRepo.List()
-	for _, level := range allLevels {
-		if level.Name == name {
-			return 0, errors.New("level with the same name already exists")
+	for _, existing := range allLevels {
+		if existing.Name == level.Name {
+			return 0, ErrLevelNameTaken
 		}
 	}
+	if level.Order == 0 {
+		level.Order = 1
+		if len(allLevels) > 0 {
+			level.Order = allLevels[len(allLevels)-1].Order + 1
+		}
+	}
+	if err := checkLadder(append(allLevels, &level)); err != nil {
+		return 0, err
+	}
 
 	// Create new level
-	level := &models.Level{Name: name}
-	return s.levelRepo.Create(level)
+	id, err := s.levelRepo.Create(&level)
+	if err != nil {
+		return 0, err
+	}
+	s.syncPlayers()
+	return id, nil
 }
 
 func (s *levelService) GetLevelByID(id int) (*models.Level, error) {
@@ -48,3 +95,176 @@ func (s *levelService) GetLevelByID(id int) (*models.Level, error) {
 func (s *levelService) GetAllLevels() ([]*models.Level, error) {
 	return s.levelRepo.List(), nil
 }
+
+func (s *levelService) UpdateLevel(id int, level models.Level) (*models.Level, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateLevel(&level); err != nil {
+		return nil, err
+	}
+	current, err := s.levelRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	level.ID = id
+	if level.Order == 0 {
+		level.Order = current.Order
+	}
+
+	ladder := []*models.Level{&level}
+	for _, existing := range s.levelRepo.List() {
+		if existing.ID == id {
+			continue
+		}
+		if existing.Name == level.Name {
+			return nil, ErrLevelNameTaken
+		}
+		ladder = append(ladder, existing)
+	}
+	if err := checkLadder(ladder); err != nil {
+		return nil, err
+	}
+
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		if err := repos.Levels.Update(&level); err != nil {
+			return err
+		}
+		// Players keep the level, but those stored with a copy of it need
+		// the new name and threshold
+		return repos.Players.MoveLevel(id, &level)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.syncPlayers()
+	return &level, nil
+}
+
+func (s *levelService) DeleteLevel(id, fallbackID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fallbackID == id {
+		return fmt.Errorf("%w: a level cannot be its own fallback", ErrInvalidLevel)
+	}
+
+	var level, fallback *models.Level
+	var moved []models.Player
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if level, err = repos.Levels.GetById(id); err != nil {
+			return err
+		}
+		if moved, err = repos.Players.GetPlayersByLevel(id); err != nil {
+			return err
+		}
+		if len(moved) > 0 {
+			if fallbackID == 0 {
+				return fmt.Errorf("%w: %d players are at %s; move them to a fallback level", ErrLevelInUse, len(moved), level.Name)
+			}
+			if fallback, err = resolveLevel(repos, &models.Level{ID: fallbackID}); err != nil {
+				return err
+			}
+			if err := repos.Players.MoveLevel(id, fallback); err != nil {
+				return err
+			}
+		}
+		return repos.Levels.Delete(id)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, player := range moved {
+		player.Level = fallback
+		s.players.logLevelChange(&player, level, "level deleted")
+	}
+	// Players who have earned experience follow the ladder rather than the
+	// fallback
+	s.syncPlayers()
+	return nil
+}
+
+// syncPlayers moves players to the levels their experience reaches now that
+// the ladder has changed. The level change already succeeded, so failures
+// are only logged; the next experience award fixes the player's level.
+func (s *levelService) syncPlayers() {
+	if _, err := s.players.SyncLevels(); err != nil {
+		log.Printf("Error moving players to their new levels: %v", err)
+	}
+}
+
+// validateLevel checks the level's fields and perks, and sorts and dedupes
+// the rooms it may reserve.
+func (s *levelService) validateLevel(level *models.Level) error {
+	if level.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidLevel)
+	}
+	if level.Order < 0 || level.MinXP < 0 {
+		return fmt.Errorf("%w: order and min_xp must not be negative", ErrInvalidLevel)
+	}
+
+	perks := &level.Perks
+	if perks.MaxWeeklyReservations < 0 || perks.ChallengeCooldownSeconds < 0 {
+		return fmt.Errorf("%w: max_weekly_reservations and challenge_cooldown_seconds must not be negative", ErrInvalidLevel)
+	}
+	if perks.ChallengeFee != nil && !perks.ChallengeFee.IsPositive() {
+		return fmt.Errorf("%w: challenge_fee must be positive", ErrInvalidLevel)
+	}
+	for _, roomID := range perks.RoomIDs {
+		if _, err := s.roomRepo.GetRoomByID(roomID); err != nil {
+			if errors.Is(err, repositories.ErrRoomNotFound) {
+				return fmt.Errorf("%w: room %d does not exist", ErrInvalidLevel, roomID)
+			}
+			return err
+		}
+	}
+	sort.Ints(perks.RoomIDs)
+	perks.RoomIDs = slices.Compact(perks.RoomIDs)
+	return nil
+}
+
+// levelPerks returns the perks of the player's level. Players without a
+// level, or whose level is gone, play by the standard rules.
+func levelPerks(levels repositories.LevelRepository, player *models.Player) (models.LevelPerks, error) {
+	level, err := playerLevel(levels, player)
+	if err != nil || level == nil {
+		return models.LevelPerks{}, err
+	}
+	return level.Perks, nil
+}
+
+// playerLevel returns the stored level the player is at, or nil when they
+// have none or it no longer exists.
+func playerLevel(levels repositories.LevelRepository, player *models.Player) (*models.Level, error) {
+	if player.Level == nil {
+		return nil, nil
+	}
+	level, err := levels.GetById(player.Level.ID)
+	if errors.Is(err, repositories.ErrLevelNotFound) {
+		return nil, nil
+	}
+	return level, err
+}
+
+// checkLadder checks that no two levels share an order and that thresholds
+// rise strictly with the order.
+func checkLadder(levels []*models.Level) error {
+	byOrder := make(map[int]*models.Level, len(levels))
+	for _, level := range levels {
+		if other, ok := byOrder[level.Order]; ok {
+			return fmt.Errorf("%w: %s and %s are both %d", ErrLevelOrderTaken, other.Name, level.Name, level.Order)
+		}
+		byOrder[level.Order] = level
+	}
+	for _, a := range levels {
+		for _, b := range levels {
+			if a.Order < b.Order && a.MinXP >= b.MinXP {
+				return fmt.Errorf("%w: %s needs %d XP but %s, which is higher, needs %d",
+					ErrLevelOutOfOrder, a.Name, a.MinXP, b.Name, b.MinXP)
+			}
+		}
+	}
+	return nil
+}
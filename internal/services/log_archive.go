@@ -0,0 +1,145 @@
+package services
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+// archiveBatchSize is how many expired logs are read at a time.
+const archiveBatchSize = 500
+
+// LogRetention says how long logs are kept. Actions without their own
+// period, including legacy free-text actions, keep logs for Default; a zero
+// period keeps them forever.
+type LogRetention struct {
+	Default time.Duration
+	Actions map[models.LogAction]time.Duration
+}
+
+// For returns how long logs with the action are kept, or zero to keep them
+// forever.
+func (r LogRetention) For(action models.LogAction) time.Duration {
+	if d, ok := r.Actions[action]; ok {
+		return d
+	}
+	return r.Default
+}
+
+// shortest returns the shortest period any log is kept for, or zero when
+// every log is kept forever.
+func (r LogRetention) shortest() time.Duration {
+	shortest := r.Default
+	for _, d := range r.Actions {
+		if d > 0 && (shortest <= 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	return max(shortest, 0)
+}
+
+// LogArchiver moves logs that are past their retention period out of the
+// log repository and into gzipped NDJSON files, one log per line.
+type LogArchiver struct {
+	logRepo   repositories.LogRepository
+	dir       string
+	retention LogRetention
+}
+
+// NewLogArchiver creates a LogArchiver that writes its files into dir.
+func NewLogArchiver(repo repositories.LogRepository, dir string, retention LogRetention) *LogArchiver {
+	return &LogArchiver{
+		logRepo:   repo,
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Archive writes every log that has expired by now to a new file named
+// logs-<time>.ndjson.gz and then deletes those logs. The file is complete
+// and synced before anything is deleted; if the deletion fails, the logs are
+// archived again on the next run. It returns how many logs were archived.
+func (a *LogArchiver) Archive(now time.Time) (int, error) {
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return 0, err
+	}
+	name := filepath.Join(a.dir, fmt.Sprintf("logs-%s.ndjson.gz", now.UTC().Format("20060102T150405Z")))
+	tmp := name + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	ids, err := a.write(file, now)
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return 0, err
+	}
+	return a.logRepo.DeleteLogs(ids)
+}
+
+// write writes the expired logs to file and returns their IDs. Logs are
+// selected by timestamp alone, so every action, known or not, is subject to
+// retention; each log is then checked against its own action's period.
+func (a *LogArchiver) write(file *os.File, now time.Time) ([]int, error) {
+	zw := gzip.NewWriter(file)
+	enc := json.NewEncoder(zw)
+
+	var ids []int
+	if shortest := a.retention.shortest(); shortest > 0 {
+		q := repositories.LogQuery{
+			EndTime: now.Add(-shortest).Unix() - 1,
+			Limit:   archiveBatchSize,
+		}
+		for {
+			logs, err := a.logRepo.Query(q)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range logs {
+				if !a.expired(entry, now) {
+					continue
+				}
+				if err := enc.Encode(entry); err != nil {
+					return nil, err
+				}
+				ids = append(ids, entry.ID)
+			}
+			if len(logs) < q.Limit {
+				break
+			}
+			after := repositories.PositionOf(logs[len(logs)-1])
+			q.After = &after
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// expired reports whether entry is past its action's retention period at now.
+func (a *LogArchiver) expired(entry models.Log, now time.Time) bool {
+	period := a.retention.For(entry.Action)
+	return period > 0 && entry.Timestamp < now.Add(-period).Unix()
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+// LogIngestConfig controls the LogIngester's queue and workers.
+type LogIngestConfig struct {
+	// QueueSize is how many accepted logs can wait to be written.
+	QueueSize int
+	// Workers is how many goroutines write logs to the repository.
+	Workers int
+	// BatchSize is the most logs a worker writes at once.
+	BatchSize int
+	// FlushInterval is how long a worker waits to fill a batch before it
+	// writes what it has.
+	FlushInterval time.Duration
+}
+
+// DefaultLogIngestConfig is used when no ingestion settings are configured.
+var DefaultLogIngestConfig = LogIngestConfig{
+	QueueSize:     10_000,
+	Workers:       4,
+	BatchSize:     100,
+	FlushInterval: 100 * time.Millisecond,
+}
+
+// MaxLogBatch is the most entries one Enqueue call accepts.
+const MaxLogBatch = 1000
+
+var (
+	ErrInvalidLogIngestConfig = fmt.Errorf("log workers and batch size must be positive and the queue must hold at least %d logs", MaxLogBatch)
+	ErrLogQueueFull           = errors.New("log queue is full, try again later")
+	ErrLogBatchTooLarge       = fmt.Errorf("at most %d logs can be sent at once", MaxLogBatch)
+	ErrLogIngesterClosed      = errors.New("log ingester is shut down")
+)
+
+// LogIngestStats reports the state of a LogIngester.
+type LogIngestStats struct {
+	QueueDepth    int `json:"queue_depth"`
+	QueueCapacity int `json:"queue_capacity"`
+	// Accepted counts logs taken into the queue.
+	Accepted uint64 `json:"accepted"`
+	// Dropped counts logs turned away because the queue was full.
+	Dropped uint64 `json:"dropped"`
+	// Written counts logs stored in the repository.
+	Written uint64 `json:"written"`
+	// Failed counts accepted logs that could not be stored.
+	Failed uint64 `json:"failed"`
+}
+
+// LogIngester writes logs asynchronously. Enqueue validates a batch of logs
+// and puts it on a bounded queue; a pool of workers takes logs off the queue
+// and stores them in bulk, so senders never wait on the repository.
+type LogIngester struct {
+	logRepo repositories.LogRepository
+	config  LogIngestConfig
+	queue   chan models.Log
+	wg      sync.WaitGroup
+
+	// mu makes checking for room and queueing a batch atomic, so a batch is
+	// queued whole or not at all.
+	mu     sync.Mutex
+	closed bool
+
+	accepted, dropped, written, failed atomic.Uint64
+}
+
+// NewLogIngester creates a LogIngester and starts its workers. The queue must
+// hold at least MaxLogBatch logs, so that every batch Enqueue accepts can fit
+// once the queue drains.
+func NewLogIngester(repo repositories.LogRepository, config LogIngestConfig) (*LogIngester, error) {
+	if config.QueueSize < MaxLogBatch || config.Workers <= 0 || config.BatchSize <= 0 {
+		return nil, ErrInvalidLogIngestConfig
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultLogIngestConfig.FlushInterval
+	}
+	in := &LogIngester{
+		logRepo: repo,
+		config:  config,
+		queue:   make(chan models.Log, config.QueueSize),
+	}
+	for i := 0; i < config.Workers; i++ {
+		in.wg.Add(1)
+		go in.work()
+	}
+	return in, nil
+}
+
+// Enqueue checks every entry as CreateLog does, stamps them with the current
+// time and queues them to be written. If any entry is invalid, or the queue
+// has no room for all of them, none are queued; a full queue returns
+// ErrLogQueueFull, which is worth retrying, and a batch that could never fit
+// returns ErrLogBatchTooLarge, which is not.
+func (in *LogIngester) Enqueue(entries []models.Log) (int, error) {
+	if len(entries) > MaxLogBatch || len(entries) > cap(in.queue) {
+		return 0, ErrLogBatchTooLarge
+	}
+	now := time.Now()
+	batch := make([]models.Log, len(entries))
+	for i, entry := range entries {
+		if !entry.Action.Valid() {
+			return 0, fmt.Errorf("entry %d: %w", i, ErrInvalidLogAction)
+		}
+		prepared, err := prepareLog(entry, now)
+		if err != nil {
+			return 0, fmt.Errorf("entry %d: %w", i, err)
+		}
+		batch[i] = prepared
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return 0, ErrLogIngesterClosed
+	}
+	// Only workers take from the queue while mu is held, so the room only
+	// grows between this check and the sends below
+	if len(in.queue)+len(batch) > cap(in.queue) {
+		in.dropped.Add(uint64(len(batch)))
+		return 0, ErrLogQueueFull
+	}
+	for _, entry := range batch {
+		in.queue <- entry
+	}
+	in.accepted.Add(uint64(len(batch)))
+	return len(batch), nil
+}
+
+// Stats returns the current queue depth and counters.
+func (in *LogIngester) Stats() LogIngestStats {
+	return LogIngestStats{
+		QueueDepth:    len(in.queue),
+		QueueCapacity: cap(in.queue),
+		Accepted:      in.accepted.Load(),
+		Dropped:       in.dropped.Load(),
+		Written:       in.written.Load(),
+		Failed:        in.failed.Load(),
+	}
+}
+
+// Close stops accepting logs and waits until the workers have written every
+// queued log.
+func (in *LogIngester) Close() {
+	in.mu.Lock()
+	if !in.closed {
+		in.closed = true
+		close(in.queue)
+	}
+	in.mu.Unlock()
+	in.wg.Wait()
+}
+
+// work writes batches of queued logs until the queue is closed and empty.
+func (in *LogIngester) work() {
+	defer in.wg.Done()
+	batch := make([]models.Log, 0, in.config.BatchSize)
+	for {
+		entry, ok := <-in.queue
+		if !ok {
+			return
+		}
+		batch = append(batch[:0], entry)
+
+		timeout := time.NewTimer(in.config.FlushInterval)
+	fill:
+		for len(batch) < in.config.BatchSize {
+			select {
+			case entry, ok := <-in.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, entry)
+			case <-timeout.C:
+				break fill
+			}
+		}
+		timeout.Stop()
+		in.flush(batch)
+	}
+}
+
+func (in *LogIngester) flush(batch []models.Log) {
+	if _, err := in.logRepo.CreateLogs(batch); err != nil {
+		in.failed.Add(uint64(len(batch)))
+		log.Printf("Error writing %d queued logs: %v", len(batch), err)
+		return
+	}
+	in.written.Add(uint64(len(batch)))
+}
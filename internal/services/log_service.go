@@ -1,26 +1,66 @@
 package services
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
-	"sync"
 )
 
+// Page sizes for QueryLogs.
+const (
+	DefaultLogLimit = 100
+	MaxLogLimit     = 1000
+
+	// exportBatchSize is how many logs ExportLogs reads at a time.
+	exportBatchSize = 500
+)
+
+var (
+	ErrInvalidLogAction = errors.New("unknown log action")
+	ErrInvalidLogQuery  = errors.New("invalid log query")
+	ErrInvalidLogCursor = errors.New("invalid log cursor")
+)
+
+// LogPage is one page of QueryLogs results. NextCursor continues the query
+// where the page ends; it is empty on the last page.
+type LogPage struct {
+	Logs       []models.Log
+	NextCursor string
+}
+
+// LogService stores the game log. It records every event the other services
+// publish.
 type LogService interface {
+	EventPublisher
 	GetAllLogs() ([]models.Log, error)
 	GetLogByID(id int) (*models.Log, error)
+	// CreateLog adds an entry by hand. The action must be one of
+	// models.LogActions and the details must match its schema.
 	CreateLog(log models.Log) (int, error)
 	GetLogsByPlayerID(playerID int) ([]models.Log, error)
-	GetLogsByAction(action string) ([]models.Log, error)
+	GetLogsByAction(action models.LogAction) ([]models.Log, error)
 	GetLogsByTimeRange(startTime, endTime int64) ([]models.Log, error)
+	// QueryLogs returns one page of the logs matching q, oldest first unless
+	// q.Descending is set. A zero q.Limit means DefaultLogLimit. cursor is
+	// the NextCursor of the previous page of the same query, or empty.
+	QueryLogs(q repositories.LogQuery, cursor string) (*LogPage, error)
+	// ExportLogs calls each for every log matching q, in the same order as
+	// QueryLogs but without a limit. Logs are read a batch at a time, so the
+	// export never holds all of them. It stops at the first error.
+	ExportLogs(q repositories.LogQuery, each func(models.Log) error) error
 	DeleteLog(id int) error
 }
 
+// logService keeps no state of its own; the repository serializes writes.
 type logService struct {
 	logRepo repositories.LogRepository
-	mu      sync.RWMutex
 }
 
 func NewLogService(repo repositories.LogRepository) LogService {
@@ -30,50 +70,183 @@ func NewLogService(repo repositories.LogRepository) LogService {
 }
 
 func (s *logService) GetAllLogs() ([]models.Log, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	return s.logRepo.GetAllLogs()
 }
 
 func (s *logService) GetLogByID(id int) (*models.Log, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	return s.logRepo.GetLogByID(id)
 }
 
-func (s *logService) CreateLog(log models.Log) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *logService) CreateLog(entry models.Log) (int, error) {
+	if !entry.Action.Valid() {
+		return 0, ErrInvalidLogAction
+	}
+	return s.create(entry, time.Now())
+}
 
-	// Add timestamps
-	now := time.Now().Unix()
-	log.Timestamp = now
-	log.CreatedAt = now
-	log.UpdatedAt = now
+// Publish records event in the game log.
+func (s *logService) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	_, err := s.create(models.Log{
+		PlayerID: event.PlayerID,
+		Action:   event.Action,
+		Details:  event.Details,
+	}, event.Time)
+	if err != nil {
+		log.Printf("Error logging %q for player %d: %v", event.Action, event.PlayerID, err)
+	}
+}
 
-	return s.logRepo.CreateLog(log)
+func (s *logService) create(entry models.Log, at time.Time) (int, error) {
+	entry, err := prepareLog(entry, at)
+	if err != nil {
+		return 0, err
+	}
+	return s.logRepo.CreateLog(entry)
+}
+
+// prepareLog checks entry's details against its action's schema and stamps
+// it as happening at at.
+func prepareLog(entry models.Log, at time.Time) (models.Log, error) {
+	details, err := entry.Action.ValidateDetails(entry.Details)
+	if err != nil {
+		return entry, err
+	}
+	entry.Details = details
+
+	// Add timestamps
+	entry.Timestamp = at.Unix()
+	entry.CreatedAt = time.Now().Unix()
+	entry.UpdatedAt = entry.CreatedAt
+	return entry, nil
 }
 
 func (s *logService) GetLogsByPlayerID(playerID int) ([]models.Log, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	return s.logRepo.GetLogsByPlayerID(playerID)
 }
 
-func (s *logService) GetLogsByAction(action string) ([]models.Log, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *logService) GetLogsByAction(action models.LogAction) ([]models.Log, error) {
 	return s.logRepo.GetLogsByAction(action)
 }
 
 func (s *logService) GetLogsByTimeRange(startTime, endTime int64) ([]models.Log, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	return s.logRepo.GetLogsByTimeRange(startTime, endTime)
 }
 
+func (s *logService) QueryLogs(q repositories.LogQuery, cursor string) (*LogPage, error) {
+	if err := validateLogQuery(q); err != nil {
+		return nil, err
+	}
+	switch {
+	case q.Limit < 0 || q.Limit > MaxLogLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLogQuery, MaxLogLimit)
+	case q.Limit == 0:
+		q.Limit = DefaultLogLimit
+	}
+	if cursor != "" {
+		after, err := decodeLogCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.After = &after
+	}
+
+	// Fetch one extra log to know whether there is another page
+	limit := q.Limit
+	q.Limit++
+	logs, err := s.logRepo.Query(q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LogPage{Logs: logs}
+	if len(logs) > limit {
+		page.Logs = logs[:limit]
+		page.NextCursor = encodeLogCursor(repositories.PositionOf(logs[limit-1]))
+	}
+	if page.Logs == nil {
+		page.Logs = []models.Log{}
+	}
+	return page, nil
+}
+
+func (s *logService) ExportLogs(q repositories.LogQuery, each func(models.Log) error) error {
+	if err := validateLogQuery(q); err != nil {
+		return err
+	}
+	q.Limit = exportBatchSize
+	for {
+		logs, err := s.logRepo.Query(q)
+		if err != nil {
+			return err
+		}
+		for _, entry := range logs {
+			if err := each(entry); err != nil {
+				return err
+			}
+		}
+		if len(logs) < q.Limit {
+			return nil
+		}
+		after := repositories.PositionOf(logs[len(logs)-1])
+		q.After = &after
+	}
+}
+
+// validateLogQuery checks the filters of q.
+func validateLogQuery(q repositories.LogQuery) error {
+	if q.Action != "" && !q.Action.Valid() {
+		return ErrInvalidLogAction
+	}
+	if q.StartTime != 0 && q.EndTime != 0 && q.StartTime > q.EndTime {
+		return fmt.Errorf("%w: start_time is after end_time", ErrInvalidLogQuery)
+	}
+	for name := range q.Details {
+		if !knownLogDetail(q.Action, name) {
+			return fmt.Errorf("%w: logs have no detail %q", ErrInvalidLogQuery, name)
+		}
+	}
+	return nil
+}
+
+// knownLogDetail reports whether logs with the action can have the named
+// detail. An empty action stands for any action.
+func knownLogDetail(action models.LogAction, name string) bool {
+	for a, schema := range models.LogDetailSchemas {
+		if _, ok := schema.Field(name); ok && (action == "" || action == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeLogCursor makes an opaque cursor for the position of the last log
+// on a page.
+func encodeLogCursor(pos repositories.LogPosition) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", pos.Timestamp, pos.ID)))
+}
+
+func decodeLogCursor(cursor string) (repositories.LogPosition, error) {
+	var pos repositories.LogPosition
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pos, ErrInvalidLogCursor
+	}
+	timestamp, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return pos, ErrInvalidLogCursor
+	}
+	if pos.Timestamp, err = strconv.ParseInt(timestamp, 10, 64); err != nil {
+		return pos, ErrInvalidLogCursor
+	}
+	if pos.ID, err = strconv.Atoi(id); err != nil {
+		return pos, ErrInvalidLogCursor
+	}
+	return pos, nil
+}
+
 func (s *logService) DeleteLog(id int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	return s.logRepo.DeleteLog(id)
 }
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"oxo_game/internal/models"
+)
+
+var (
+	ErrPaymentDeclined = errors.New("payment declined")
+)
+
+// PaymentProvider charges an external payment method, such as a card or an
+// app store, when a player tops up their balance.
+type PaymentProvider interface {
+	// Method is the name clients pass as a payment's method.
+	Method() string
+	// Charge takes amount from the payment source described by details and
+	// returns the provider's reference for the charge.
+	Charge(playerID int, amount models.Money, details string) (string, error)
+	// Refund reverses a charge, e.g. when it could not be recorded.
+	Refund(reference string) error
+}
+
+// FakePaymentDeclineDetails makes FakePaymentProvider decline a charge.
+const FakePaymentDeclineDetails = "decline"
+
+// FakePaymentProvider is an in-process provider for local testing. It accepts
+// every charge except those whose details are FakePaymentDeclineDetails.
+type FakePaymentProvider struct {
+	mu      sync.Mutex
+	charges map[string]models.Money
+	nextRef int
+}
+
+func NewFakePaymentProvider() *FakePaymentProvider {
+	return &FakePaymentProvider{
+		charges: make(map[string]models.Money),
+	}
+}
+
+func (p *FakePaymentProvider) Method() string {
+	return "fake"
+}
+
+func (p *FakePaymentProvider) Charge(playerID int, amount models.Money, details string) (string, error) {
+	if details == FakePaymentDeclineDetails {
+		return "", ErrPaymentDeclined
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextRef++
+	reference := fmt.Sprintf("fake-%d", p.nextRef)
+	p.charges[reference] = amount
+	return reference, nil
+}
+
+func (p *FakePaymentProvider) Refund(reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.charges[reference]; !ok {
+		return fmt.Errorf("unknown charge %q", reference)
+	}
+	delete(p.charges, reference)
+	return nil
+}
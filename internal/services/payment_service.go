@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"oxo_game/internal/models"
+	"oxo_game/internal/repositories"
+)
+
+var (
+	ErrInvalidPaymentAmount     = errors.New("payment amount must be positive")
+	ErrUnsupportedPaymentMethod = errors.New("unsupported payment method")
+	ErrTopUpsDisabled           = errors.New("top-ups are disabled")
+)
+
+type PaymentService interface {
+	// TopUp charges the player's payment method and credits their balance.
+	TopUp(playerID int, method string, amount models.Money, details string) (*models.Payment, error)
+	GetPaymentByID(id int) (*models.Payment, error)
+	ListPaymentsByPlayer(playerID int) ([]*models.Payment, error)
+	// Methods lists the payment methods players can top up with.
+	Methods() []string
+}
+
+type paymentService struct {
+	paymentRepo repositories.PaymentRepository
+	playerRepo  repositories.PlayerRepository
+	uow         repositories.UnitOfWork
+	providers   map[string]PaymentProvider
+}
+
+func NewPaymentService(paymentRepo repositories.PaymentRepository, playerRepo repositories.PlayerRepository, uow repositories.UnitOfWork, providers ...PaymentProvider) PaymentService {
+	s := &paymentService{
+		paymentRepo: paymentRepo,
+		playerRepo:  playerRepo,
+		uow:         uow,
+		providers:   make(map[string]PaymentProvider),
+	}
+	for _, provider := range providers {
+		s.providers[provider.Method()] = provider
+	}
+	return s
+}
+
+func (s *paymentService) TopUp(playerID int, method string, amount models.Money, details string) (*models.Payment, error) {
+	if !amount.IsPositive() {
+		return nil, ErrInvalidPaymentAmount
+	}
+	if len(s.providers) == 0 {
+		return nil, ErrTopUpsDisabled
+	}
+	provider, ok := s.providers[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedPaymentMethod, method)
+	}
+	if _, err := s.playerRepo.GetPlayerByID(playerID); err != nil {
+		return nil, err
+	}
+
+	reference, err := provider.Charge(playerID, amount, details)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &models.Payment{
+		PlayerID: playerID,
+		Type:     models.PaymentTypeTopUp,
+		Method:   method,
+		Amount:   amount,
+		Details:  details,
+	}
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		if err := repos.Players.CreditBalance(playerID, amount); err != nil {
+			return err
+		}
+		paymentID, err := repos.Payments.Create(payment)
+		if err != nil {
+			return err
+		}
+		_, err = postLedger(repos, models.LedgerKindTopUp, details, fmt.Sprintf("payment:%d", paymentID),
+			models.LedgerEntry{Account: models.PlayerAccount(playerID), Amount: amount},
+			models.LedgerEntry{Account: models.ExternalAccount(method), Amount: amount.Neg()})
+		return err
+	})
+	if err != nil {
+		// The player paid but was not credited, so give the money back
+		if refundErr := provider.Refund(reference); refundErr != nil {
+			log.Printf("Error refunding %s charge %s: %v", method, reference, refundErr)
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *paymentService) GetPaymentByID(id int) (*models.Payment, error) {
+	return s.paymentRepo.GetById(id)
+}
+
+func (s *paymentService) ListPaymentsByPlayer(playerID int) ([]*models.Payment, error) {
+	if _, err := s.playerRepo.GetPlayerByID(playerID); err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.ListByPlayer(playerID), nil
+}
+
+func (s *paymentService) Methods() []string {
+	methods := make([]string, 0, len(s.providers))
+	for method := range s.providers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// recordWalletPayment records a balance change made by the game itself, such
+// as a challenge fee or a jackpot payout.
+func recordWalletPayment(repos repositories.Repositories, playerID int, paymentType string, amount models.Money) error {
+	_, err := repos.Payments.Create(&models.Payment{
+		PlayerID: playerID,
+		Type:     paymentType,
+		Method:   models.PaymentMethodWallet,
+		Amount:   amount,
+	})
+	return err
+}
@@ -2,20 +2,31 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"sync"
+
 	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
 )
 
 var (
-	ErrPlayerNotFound = errors.New("player not found")
+	ErrPlayerNotFound = repositories.ErrPlayerNotFound
+	ErrInvalidRole    = errors.New("role must be player, operator or admin")
+	ErrUnknownLevel   = errors.New("level does not exist")
 )
 
 type PlayerService struct {
-	repo repositories.PlayerRepository
+	repo      repositories.PlayerRepository
+	levelRepo repositories.LevelRepository
+	uow       repositories.UnitOfWork
+	events    EventPublisher
+
+	// levelMu keeps level changes for the same XP from racing each other
+	levelMu sync.Mutex
 }
 
-func NewPlayerService(repo repositories.PlayerRepository) *PlayerService {
-	return &PlayerService{repo: repo}
+func NewPlayerService(repo repositories.PlayerRepository, levelRepo repositories.LevelRepository, uow repositories.UnitOfWork, events EventPublisher) *PlayerService {
+	return &PlayerService{repo: repo, levelRepo: levelRepo, uow: uow, events: events}
 }
 
 func (s *PlayerService) GetAllPlayers() ([]models.Player, error) {
@@ -26,14 +37,234 @@ func (s *PlayerService) GetPlayerByID(id int) (*models.Player, error) {
 	return s.repo.GetPlayerByID(id)
 }
 
+// CreatePlayer creates a player, by default with the player role. A starting
+// balance is booked as an opening balance adjustment so that it shows up in
+// the player's ledger.
 func (s *PlayerService) CreatePlayer(player models.Player) (int, error) {
-	return s.repo.CreatePlayer(player)
+	if player.Role == "" {
+		player.Role = models.RolePlayer
+	}
+	if !models.ValidRole(player.Role) {
+		return 0, ErrInvalidRole
+	}
+	// Experience is only earned, through AwardXP
+	player.XP = 0
+
+	var id int
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if player.Level, err = resolveLevel(repos, player.Level); err != nil {
+			return err
+		}
+		id, err = repos.Players.CreatePlayer(player)
+		if err != nil || player.Balance.IsZero() {
+			return err
+		}
+		if err := recordWalletPayment(repos, id, models.PaymentTypeAdjustment, player.Balance); err != nil {
+			return err
+		}
+		_, err = postLedger(repos, models.LedgerKindAdjustment, "opening balance", "",
+			models.LedgerEntry{Account: models.PlayerAccount(id), Amount: player.Balance},
+			models.LedgerEntry{Account: models.AccountAdjustments, Amount: player.Balance.Neg()})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.events.Publish(Event{
+		Action:   models.LogActionRegister,
+		PlayerID: id,
+		Details:  models.LogDetails{"role": string(player.Role)},
+	})
+	return id, nil
+}
+
+// UpdatePlayer updates a player's details. The balance is left untouched; it
+// only changes through ledger transactions. The role only changes through
+// SetPlayerRole, and experience only through AwardXP. The stored level is
+// kept unless setLevel is true, which is only for admins; players otherwise
+// move level by earning experience. A player given a level has their
+// experience raised to its threshold, so the next award does not drop them
+// back down the ladder, and the change is logged.
+func (s *PlayerService) UpdatePlayer(id int, player models.Player, setLevel bool) error {
+	s.levelMu.Lock()
+	defer s.levelMu.Unlock()
+
+	var previous *models.Level
+	changed := false
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		current, err := repos.Players.GetPlayerByID(id)
+		if err != nil {
+			return err
+		}
+		if !setLevel {
+			player.Level = current.Level
+		} else if player.Level, err = resolveLevel(repos, player.Level); err != nil {
+			return err
+		}
+		player.ID = id
+		player.Role = current.Role
+		player.XP = current.XP
+		if err := repos.Players.UpdatePlayer(id, player); err != nil {
+			return err
+		}
+		if sameLevel(current.Level, player.Level) {
+			return nil
+		}
+		previous, changed = current.Level, true
+		if player.Level != nil {
+			player.XP, err = repos.Players.RaiseXP(id, player.Level.MinXP)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		s.logLevelChange(&player, previous, "set by admin")
+	}
+	return nil
+}
+
+// SetPlayerRole gives a player a new role.
+func (s *PlayerService) SetPlayerRole(id int, role string) (*models.Player, error) {
+	if !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	var player *models.Player
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		var err error
+		if player, err = repos.Players.GetPlayerByID(id); err != nil {
+			return err
+		}
+		player.Role = role
+		return repos.Players.UpdatePlayer(id, *player)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// AwardXP adds xp, which is negative for a penalty, to the player's
+// experience. The player then moves to the highest level whose threshold
+// they have reached, and the level change is logged; reason says what the
+// experience was for.
+func (s *PlayerService) AwardXP(id int, xp int64, reason string) (*models.Player, error) {
+	s.levelMu.Lock()
+	defer s.levelMu.Unlock()
+
+	return s.syncLevel(id, xp, s.levelRepo.List(), reason)
+}
+
+// SyncLevels moves every player who has earned experience to the level it
+// reaches, for when the levels themselves change. It returns how many
+// players changed level.
+func (s *PlayerService) SyncLevels() (int, error) {
+	s.levelMu.Lock()
+	defer s.levelMu.Unlock()
+
+	players, err := s.repo.GetAllPlayers()
+	if err != nil {
+		return 0, err
+	}
+	levels := s.levelRepo.List()
+	changed := 0
+	for _, player := range players {
+		if player.XP == 0 || sameLevel(player.Level, models.LevelForXP(levels, player.XP)) {
+			continue
+		}
+		if _, err := s.syncLevel(player.ID, 0, levels, "levels changed"); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// syncLevel adds xp, if any, to the player's experience and moves them to
+// the level it reaches among levels in the same unit of work, then logs the
+// change.
+func (s *PlayerService) syncLevel(id int, xp int64, levels []*models.Level, reason string) (*models.Player, error) {
+	var player, previous *models.Player
+	err := s.uow.Do(func(repos repositories.Repositories) error {
+		if xp != 0 {
+			if _, err := repos.Players.AddXP(id, xp); err != nil {
+				return err
+			}
+		}
+		var err error
+		if player, err = repos.Players.GetPlayerByID(id); err != nil {
+			return err
+		}
+		level := models.LevelForXP(levels, player.XP)
+		if sameLevel(player.Level, level) {
+			return nil
+		}
+		before := *player
+		previous = &before
+		player.Level = level
+		return repos.Players.UpdatePlayer(id, *player)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		s.logLevelChange(player, previous.Level, reason)
+	}
+	return player, nil
+}
+
+// logLevelChange logs that player, who was at level from, moved to the level
+// they are at now.
+func (s *PlayerService) logLevelChange(player *models.Player, from *models.Level, reason string) {
+	details := models.LogDetails{"xp": player.XP, "reason": reason}
+	if from != nil {
+		details["from_level_id"] = from.ID
+		details["from_level"] = from.Name
+	}
+	if player.Level != nil {
+		details["level_id"] = player.Level.ID
+		details["level"] = player.Level.Name
+	}
+	s.events.Publish(Event{
+		Action:   models.LogActionLevelChanged,
+		PlayerID: player.ID,
+		Details:  details,
+	})
+}
+
+// resolveLevel looks up the level a player is being given by its ID, so a
+// player can only reference a level that exists.
+func resolveLevel(repos repositories.Repositories, level *models.Level) (*models.Level, error) {
+	if level == nil {
+		return nil, nil
+	}
+	stored, err := repos.Levels.GetById(level.ID)
+	if errors.Is(err, repositories.ErrLevelNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownLevel, level.ID)
+	}
+	return stored, err
 }
 
-func (s *PlayerService) UpdatePlayer(id int, player models.Player) error {
-	return s.repo.UpdatePlayer(id, player)
+func sameLevel(a, b *models.Level) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID
 }
 
+// DeletePlayer deletes a player together with their credentials and
+// sessions, so nobody can sign in as a player that no longer exists.
 func (s *PlayerService) DeletePlayer(id int) error {
-	return s.repo.DeletePlayer(id)
+	return s.uow.Do(func(repos repositories.Repositories) error {
+		if err := repos.Players.DeletePlayer(id); err != nil {
+			return err
+		}
+		return repos.Auth.DeletePlayerAuth(id)
+	})
 }
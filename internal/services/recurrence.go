@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSeriesOccurrences caps how many reservations one series can expand to.
+const maxSeriesOccurrences = 100
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// recurrence is the part of an RFC 5545 RRULE that reservation series
+// support: FREQ=DAILY or WEEKLY, INTERVAL, BYDAY (weekly rules only) and
+// exactly one of COUNT or UNTIL. Weeks start on Monday.
+type recurrence struct {
+	weekly   bool
+	interval int
+	byDay    map[time.Weekday]bool
+	count    int
+	until    time.Time
+}
+
+// parseRecurrence parses a rule such as FREQ=WEEKLY;BYDAY=TU;COUNT=12. An
+// "RRULE:" prefix is allowed.
+func parseRecurrence(rule string) (*recurrence, error) {
+	r := &recurrence{interval: 1}
+	var freq string
+
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRecurrence, part)
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			freq = value
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err == nil && r.interval < 1 {
+				err = errors.New("must be at least 1")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err == nil && r.count < 1 {
+				err = errors.New("must be at least 1")
+			}
+		case "UNTIL":
+			// Only the date of a DATE-TIME UNTIL matters
+			r.until, err = time.Parse("20060102", value[:min(len(value), 8)])
+		case "BYDAY":
+			r.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					err = fmt.Errorf("unknown day %q", day)
+					break
+				}
+				r.byDay[weekday] = true
+			}
+		default:
+			err = errors.New("is not supported")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %v", ErrInvalidRecurrence, name, err)
+		}
+	}
+
+	switch freq {
+	case "WEEKLY":
+		r.weekly = true
+	case "DAILY":
+		if r.byDay != nil {
+			return nil, fmt.Errorf("%w: BYDAY needs FREQ=WEEKLY", ErrInvalidRecurrence)
+		}
+	default:
+		return nil, fmt.Errorf("%w: FREQ must be DAILY or WEEKLY", ErrInvalidRecurrence)
+	}
+	if (r.count == 0) == r.until.IsZero() {
+		return nil, fmt.Errorf("%w: exactly one of COUNT or UNTIL is required", ErrInvalidRecurrence)
+	}
+	if r.count > maxSeriesOccurrences {
+		return nil, fmt.Errorf("%w: at most %d occurrences", ErrInvalidRecurrence, maxSeriesOccurrences)
+	}
+	return r, nil
+}
+
+// dates returns the days the rule falls on, starting from start, which is a
+// midnight UTC date. A weekly rule without BYDAY repeats on start's weekday.
+func (r *recurrence) dates(start time.Time) ([]time.Time, error) {
+	byDay := r.byDay
+	if r.weekly && byDay == nil {
+		byDay = map[time.Weekday]bool{start.Weekday(): true}
+	}
+
+	// Walk one day at a time through each period the rule is active in
+	period := 1
+	if r.weekly {
+		period = 7
+	}
+	periodStart := start
+	if r.weekly {
+		periodStart = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+
+	var dates []time.Time
+	for {
+		for i := 0; i < period; i++ {
+			day := periodStart.AddDate(0, 0, i)
+			if day.Before(start) || (r.weekly && !byDay[day.Weekday()]) {
+				continue
+			}
+			if !r.until.IsZero() && day.After(r.until) {
+				return dates, nil
+			}
+			if len(dates) == maxSeriesOccurrences {
+				return nil, fmt.Errorf("%w: at most %d occurrences", ErrInvalidRecurrence, maxSeriesOccurrences)
+			}
+			dates = append(dates, day)
+			if len(dates) == r.count {
+				return dates, nil
+			}
+		}
+		periodStart = periodStart.AddDate(0, 0, period*r.interval)
+	}
+}
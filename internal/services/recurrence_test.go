@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceDates(t *testing.T) {
+	// 2024-07-03 是星期三
+	start := time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rule string
+		want []string
+	}{
+		{
+			name: "daily count",
+			rule: "FREQ=DAILY;COUNT=3",
+			want: []string{"2024-07-03", "2024-07-04", "2024-07-05"},
+		},
+		{
+			name: "daily interval",
+			rule: "FREQ=DAILY;INTERVAL=3;COUNT=3",
+			want: []string{"2024-07-03", "2024-07-06", "2024-07-09"},
+		},
+		{
+			name: "daily until is inclusive",
+			rule: "FREQ=DAILY;UNTIL=20240705",
+			want: []string{"2024-07-03", "2024-07-04", "2024-07-05"},
+		},
+		{
+			name: "until date-time only uses the date",
+			rule: "RRULE:FREQ=DAILY;UNTIL=20240704T235959Z",
+			want: []string{"2024-07-03", "2024-07-04"},
+		},
+		{
+			name: "weekly defaults to the start's weekday",
+			rule: "FREQ=WEEKLY;COUNT=3",
+			want: []string{"2024-07-03", "2024-07-10", "2024-07-17"},
+		},
+		{
+			name: "weekly interval",
+			rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			want: []string{"2024-07-03", "2024-07-17", "2024-07-31"},
+		},
+		{
+			name: "byday skips days before the start in the first week",
+			rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			want: []string{"2024-07-03", "2024-07-05", "2024-07-08", "2024-07-10", "2024-07-12"},
+		},
+		{
+			name: "byday across a week boundary",
+			rule: "FREQ=WEEKLY;BYDAY=SU,MO;COUNT=4",
+			want: []string{"2024-07-07", "2024-07-08", "2024-07-14", "2024-07-15"},
+		},
+		{
+			name: "byday with interval skips whole weeks",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20240725",
+			want: []string{"2024-07-04", "2024-07-16", "2024-07-18"},
+		},
+		{
+			name: "lowercase rule",
+			rule: "freq=weekly;byday=fr;count=2",
+			want: []string{"2024-07-05", "2024-07-12"},
+		},
+		{
+			name: "until before the start",
+			rule: "FREQ=DAILY;UNTIL=20240701",
+			want: nil,
+		},
+		{
+			name: "weekly until before the first byday",
+			rule: "FREQ=WEEKLY;BYDAY=MO;UNTIL=20240707",
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := parseRecurrence(test.rule)
+			if err != nil {
+				t.Fatalf("Error parsing %q: %v", test.rule, err)
+			}
+			dates, err := r.dates(start)
+			if err != nil {
+				t.Fatalf("Error expanding %q: %v", test.rule, err)
+			}
+			got := make([]string, len(dates))
+			for i, date := range dates {
+				got[i] = date.Format("2006-01-02")
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("Expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRecurrenceDates_Cap(t *testing.T) {
+	// UNTIL can reach further than the cap; the series is rejected rather
+	// than cut short
+	r, err := parseRecurrence("FREQ=DAILY;UNTIL=20250101")
+	if err != nil {
+		t.Fatalf("Error parsing rule: %v", err)
+	}
+	start := time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)
+	if _, err := r.dates(start); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("Expected ErrInvalidRecurrence over the cap, got %v", err)
+	}
+
+	// Exactly the cap is fine, by COUNT or by UNTIL
+	for _, rule := range []string{"FREQ=DAILY;COUNT=100", "FREQ=DAILY;UNTIL=20241010"} {
+		r, err := parseRecurrence(rule)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", rule, err)
+		}
+		dates, err := r.dates(start)
+		if err != nil || len(dates) != maxSeriesOccurrences {
+			t.Errorf("%s: expected %d dates, got %d (%v)", rule, maxSeriesOccurrences, len(dates), err)
+		}
+	}
+}
+
+func TestParseRecurrence_Invalid(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=MONTHLY;COUNT=3",
+		"FREQ=YEARLY;COUNT=3",
+		"COUNT=3",
+		"FREQ=DAILY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20240710",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=abc",
+		"FREQ=DAILY;COUNT=101",
+		"FREQ=DAILY;INTERVAL=0;COUNT=3",
+		"FREQ=DAILY;UNTIL=2024-07-10",
+		"FREQ=DAILY;BYDAY=MO;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=XX;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=MO,;COUNT=3",
+		"FREQ=WEEKLY;BYMONTH=1;COUNT=3",
+		"FREQ=WEEKLY;COUNT",
+		"FREQ=WEEKLY;COUNT=;",
+		"FREQ=WEEKLY;;COUNT=3",
+	} {
+		if _, err := parseRecurrence(rule); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("parseRecurrence(%q): expected ErrInvalidRecurrence, got %v", rule, err)
+		}
+	}
+}
@@ -1,35 +1,180 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
 )
 
+// checkInOpensBefore is how long before its slot starts a reservation can be
+// checked in.
+const checkInOpensBefore = 15 * time.Minute
+
+var (
+	ErrInvalidReservation  = errors.New("reservation needs a date and a time such as 14:00 or 2:00 PM")
+	ErrRoomUnavailable     = errors.New("room is not taking reservations")
+	ErrReservationConflict = errors.New("player already has a reservation in this room at that time")
+	ErrRoomFull            = errors.New("room is fully booked at that time")
+	ErrSlotEnded           = errors.New("reservation slot has already ended")
+	ErrOutsideOpeningHours = errors.New("reservation slot is outside the room's opening hours")
+	ErrInvalidDateRange    = errors.New("availability range must run forwards and span at most 31 days")
+	ErrSeriesConflict      = errors.New("reservation series could not be booked")
+	ErrInvalidTransition   = errors.New("reservation cannot change to that status")
+	ErrCheckInClosed       = errors.New("check-in is only open from 15 minutes before the slot until it ends")
+	ErrRoomNotAllowed      = errors.New("player's level may not reserve this room")
+	ErrWeeklyLimitReached  = errors.New("player's level allows no more reservations that week")
+)
+
+// maxAvailabilityDays is the longest range GetAvailability covers.
+const maxAvailabilityDays = 31
+
+// slotTimeLayouts are the accepted formats of a reservation's time.
+var slotTimeLayouts = []string{"15:04", "3:04 PM", "3:04PM"}
+
+// ReservationSeriesResult is a reservation series with its occurrences.
+// Conflicts lists the occurrences that could not be booked when the series
+// was created.
+type ReservationSeriesResult struct {
+	Series       *models.ReservationSeries `json:"series,omitempty"`
+	Reservations []*models.Reservation     `json:"reservations"`
+	Conflicts    []OccurrenceConflict      `json:"conflicts,omitempty"`
+}
+
+// OccurrenceConflict explains why one occurrence of a series was not booked.
+type OccurrenceConflict struct {
+	Date  string `json:"date"`
+	Error string `json:"error"`
+}
+
+// RoomAvailability lists a room's slots, day by day.
+type RoomAvailability struct {
+	RoomID int               `json:"room_id"`
+	Days   []DayAvailability `json:"days"`
+}
+
+// DayAvailability lists the slots of one day between the room's opening
+// hours.
+type DayAvailability struct {
+	Date  string             `json:"date"`
+	Slots []SlotAvailability `json:"slots"`
+}
+
+// SlotAvailability describes how much of one slot is booked. A slot is
+// available when it has not ended, the room is bookable and fewer than
+// capacity players hold reservations overlapping it.
+type SlotAvailability struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Booked    int    `json:"booked"`
+	Capacity  int    `json:"capacity"`
+	Available bool   `json:"available"`
+}
+
+// reservationLogActions is the game log action written when a reservation
+// moves to each status.
+var reservationLogActions = map[string]models.LogAction{
+	models.ReservationStatusPending:   models.LogActionReservationCreated,
+	models.ReservationStatusConfirmed: models.LogActionReservationConfirmed,
+	models.ReservationStatusCheckedIn: models.LogActionCheckIn,
+	models.ReservationStatusCompleted: models.LogActionReservationCompleted,
+	models.ReservationStatusCancelled: models.LogActionReservationCancelled,
+	models.ReservationStatusNoShow:    models.LogActionNoShow,
+}
+
 type ReservationService interface {
+	// CreateReservation books a pending slot of the room's length starting at
+	// timeSlot. The room must exist and be bookable, the player must exist
+	// and hold no overlapping reservation in the room, and the room must have
+	// capacity left for the whole slot.
 	CreateReservation(roomID int, date time.Time, timeSlot string, playerID int) (int, error)
 	GetReservationByID(id int) (*models.Reservation, error)
-	ListReservations() []*models.Reservation
-	ListReservationsByRoomAndDate(roomID int, date time.Time) []*models.Reservation
+	// ListReservations returns the reservations for roomID on date, oldest
+	// first. A zero roomID or date matches every room or date.
+	ListReservations(roomID int, date time.Time) []*models.Reservation
+	// GetAvailability returns the room's slots for every day from from to to,
+	// inclusive.
+	GetAvailability(roomID int, from, to time.Time) (*RoomAvailability, error)
+	ConfirmReservation(id int) (*models.Reservation, error)
+	CancelReservation(id int) (*models.Reservation, error)
+	// RescheduleReservation moves a pending or confirmed reservation to
+	// another slot in the same room, subject to the same checks as a new one.
+	RescheduleReservation(id int, date time.Time, timeSlot string) (*models.Reservation, error)
+	// CheckIn records that the player arrived for a confirmed reservation.
+	CheckIn(id int) (*models.Reservation, error)
+	// CreateReservationSeries books every occurrence of rule, starting from
+	// startDate, that passes the checks CreateReservation makes. Occurrences
+	// that fail are reported as conflicts. If every occurrence fails, or any
+	// does when allOrNothing is set, nothing is booked and the result comes
+	// back with ErrSeriesConflict.
+	CreateReservationSeries(roomID int, startDate time.Time, timeSlot string, playerID int, rule string, allOrNothing bool) (*ReservationSeriesResult, error)
+	GetReservationSeries(id int) (*ReservationSeriesResult, error)
+	// CancelReservationSeries cancels every pending or confirmed occurrence
+	// of the series that has not started yet.
+	CancelReservationSeries(id int) (*ReservationSeriesResult, error)
+	// SweepReservations closes the reservations whose slot ended before now:
+	// checked-in ones are completed and the rest become no-shows. It returns
+	// how many it closed.
+	SweepReservations(now time.Time) (int, error)
 }
 
 type reservationService struct {
 	reservationRepo repositories.ReservationRepository
+	roomRepo        repositories.RoomRepository
+	playerRepo      repositories.PlayerRepository
+	levelRepo       repositories.LevelRepository
+	uow             repositories.UnitOfWork
+	events          EventPublisher
+	mu              sync.Mutex
 }
 
-func NewReservationService(repo repositories.ReservationRepository) ReservationService {
+func NewReservationService(repo repositories.ReservationRepository, roomRepo repositories.RoomRepository, playerRepo repositories.PlayerRepository, levelRepo repositories.LevelRepository, uow repositories.UnitOfWork, events EventPublisher) ReservationService {
 	return &reservationService{
 		reservationRepo: repo,
+		roomRepo:        roomRepo,
+		playerRepo:      playerRepo,
+		levelRepo:       levelRepo,
+		uow:             uow,
+		events:          events,
 	}
 }
 
 func (s *reservationService) CreateReservation(roomID int, date time.Time, timeSlot string, playerID int) (int, error) {
+	start, err := parseSlotTime(timeSlot)
+	if err != nil || date.IsZero() {
+		return 0, ErrInvalidReservation
+	}
+
+	// Serialize bookings so two requests cannot both take the last place
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return 0, err
+	}
+	perks, err := s.playerPerks(playerID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkSlot(room, date, start, playerID, 0); err != nil {
+		return 0, err
+	}
+	if err := checkPerks(perks, room, date, s.weeklyBookings(perks, playerID, 0, date)); err != nil {
+		return 0, err
+	}
+
 	reservation := &models.Reservation{
 		RoomID:    roomID,
 		Date:      date,
 		Time:      timeSlot,
 		PlayerID:  playerID,
+		Status:    models.ReservationStatusPending,
 		CreatedAt: time.Now(),
 	}
 
@@ -37,18 +182,580 @@ func (s *reservationService) CreateReservation(roomID int, date time.Time, timeS
 	if err != nil {
 		return 0, err
 	}
+	s.logReservation(reservation, reservationLogActions[reservation.Status], nil)
 
 	return id, nil
 }
 
+func (s *reservationService) CreateReservationSeries(roomID int, startDate time.Time, timeSlot string, playerID int, rule string, allOrNothing bool) (*ReservationSeriesResult, error) {
+	start, err := parseSlotTime(timeSlot)
+	if err != nil || startDate.IsZero() {
+		return nil, ErrInvalidReservation
+	}
+	recurrence, err := parseRecurrence(rule)
+	if err != nil {
+		return nil, err
+	}
+	dates, err := recurrence.dates(utcDate(startDate))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	perks, err := s.playerPerks(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences fall on different days, so checking each one against the
+	// stored reservations is enough; only the weekly cap also has to count
+	// the occurrences booked before it
+	result := &ReservationSeriesResult{Reservations: make([]*models.Reservation, 0)}
+	var free []time.Time
+	booked := s.weeklyBookings(perks, playerID, 0, dates...)
+	for _, date := range dates {
+		err := s.checkSlot(room, date, start, playerID, 0)
+		if err == nil {
+			err = checkPerks(perks, room, date, booked)
+		}
+		if err != nil {
+			result.Conflicts = append(result.Conflicts, OccurrenceConflict{
+				Date:  date.Format("2006-01-02"),
+				Error: err.Error(),
+			})
+			continue
+		}
+		free = append(free, date)
+		booked[weekOf(date)]++
+	}
+	if len(free) == 0 || (allOrNothing && len(result.Conflicts) > 0) {
+		return result, ErrSeriesConflict
+	}
+
+	// Book the series and its occurrences together, so a failure part way
+	// leaves nothing behind
+	series := &models.ReservationSeries{
+		RoomID:    roomID,
+		PlayerID:  playerID,
+		Time:      timeSlot,
+		StartDate: utcDate(startDate),
+		Rule:      rule,
+	}
+	var reservations []*models.Reservation
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		if _, err := repos.Reservations.CreateSeries(series); err != nil {
+			return err
+		}
+		for _, date := range free {
+			reservation := &models.Reservation{
+				RoomID:    roomID,
+				Date:      date,
+				Time:      timeSlot,
+				PlayerID:  playerID,
+				Status:    models.ReservationStatusPending,
+				SeriesID:  series.ID,
+				CreatedAt: time.Now(),
+			}
+			if _, err := repos.Reservations.Create(reservation); err != nil {
+				return err
+			}
+			reservations = append(reservations, reservation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Series = series
+	for _, reservation := range reservations {
+		s.logReservation(reservation, reservationLogActions[reservation.Status],
+			models.LogDetails{"series_id": series.ID})
+		result.Reservations = append(result.Reservations, reservation)
+	}
+	return result, nil
+}
+
+func (s *reservationService) GetReservationSeries(id int) (*ReservationSeriesResult, error) {
+	series, err := s.reservationRepo.GetSeriesByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &ReservationSeriesResult{
+		Series:       series,
+		Reservations: s.reservationRepo.ListBySeries(id),
+	}, nil
+}
+
+func (s *reservationService) CancelReservationSeries(id int) (*ReservationSeriesResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series, err := s.reservationRepo.GetSeriesByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var upcoming []int
+	for _, reservation := range s.reservationRepo.ListBySeries(id) {
+		if !reservation.CanTransitionTo(models.ReservationStatusCancelled) {
+			continue
+		}
+		start, _, err := s.slotBounds(reservation)
+		if err != nil || !now.Before(start) {
+			continue
+		}
+		upcoming = append(upcoming, reservation.ID)
+	}
+
+	// Cancel the occurrences together, so a failure part way cancels none
+	var cancelled []statusChange
+	err = s.uow.Do(func(repos repositories.Repositories) error {
+		for _, reservationID := range upcoming {
+			change, err := changeStatus(repos.Reservations, reservationID, models.ReservationStatusCancelled)
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range cancelled {
+		s.logStatusChange(change)
+	}
+
+	return &ReservationSeriesResult{
+		Series:       series,
+		Reservations: s.reservationRepo.ListBySeries(id),
+	}, nil
+}
+
 func (s *reservationService) GetReservationByID(id int) (*models.Reservation, error) {
 	return s.reservationRepo.GetById(id)
 }
 
-func (s *reservationService) ListReservations() []*models.Reservation {
+func (s *reservationService) ListReservations(roomID int, date time.Time) []*models.Reservation {
+	switch {
+	case roomID != 0 && !date.IsZero():
+		return s.reservationRepo.ListByRoomAndDate(roomID, date)
+	case roomID != 0:
+		return s.reservationRepo.ListByRoom(roomID)
+	case !date.IsZero():
+		reservations := make([]*models.Reservation, 0)
+		for _, reservation := range s.reservationRepo.List() {
+			if reservation.Date.Equal(date) {
+				reservations = append(reservations, reservation)
+			}
+		}
+		return reservations
+	}
 	return s.reservationRepo.List()
 }
 
-func (s *reservationService) ListReservationsByRoomAndDate(roomID int, date time.Time) []*models.Reservation {
-	return s.reservationRepo.ListByRoomAndDate(roomID, date)
+func (s *reservationService) GetAvailability(roomID int, from, to time.Time) (*RoomAvailability, error) {
+	from, to = utcDate(from), utcDate(to)
+	if to.Before(from) || to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		return nil, ErrInvalidDateRange
+	}
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	opens, closes, err := openingHours(room)
+	if err != nil {
+		return nil, err
+	}
+	slot := slotLength(room)
+	capacity := max(room.Capacity, 1)
+	now := time.Now()
+
+	availability := &RoomAvailability{RoomID: room.ID, Days: make([]DayAvailability, 0)}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		var starts []time.Duration
+		for _, reservation := range s.reservationRepo.ListByRoomAndDate(room.ID, day) {
+			if start, err := parseSlotTime(reservation.Time); err == nil && reservation.Active() {
+				starts = append(starts, start)
+			}
+		}
+
+		dayAvailability := DayAvailability{Date: day.Format("2006-01-02"), Slots: make([]SlotAvailability, 0)}
+		for start := opens; start+slot <= closes; start += slot {
+			var overlapping []time.Duration
+			for _, existing := range starts {
+				if existing < start+slot && start < existing+slot {
+					overlapping = append(overlapping, existing)
+				}
+			}
+			booked := peakOccupancy(overlapping, start, slot)
+			dayAvailability.Slots = append(dayAvailability.Slots, SlotAvailability{
+				Start:     formatSlotTime(start),
+				End:       formatSlotTime(start + slot),
+				Booked:    booked,
+				Capacity:  capacity,
+				Available: room.Bookable() && booked < capacity && now.Before(day.Add(start+slot)),
+			})
+		}
+		availability.Days = append(availability.Days, dayAvailability)
+	}
+	return availability, nil
+}
+
+func (s *reservationService) ConfirmReservation(id int) (*models.Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transition(id, models.ReservationStatusConfirmed)
+}
+
+func (s *reservationService) CancelReservation(id int) (*models.Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transition(id, models.ReservationStatusCancelled)
+}
+
+func (s *reservationService) RescheduleReservation(id int, date time.Time, timeSlot string) (*models.Reservation, error) {
+	start, err := parseSlotTime(timeSlot)
+	if err != nil || date.IsZero() {
+		return nil, ErrInvalidReservation
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, err := s.reservationRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.Status != models.ReservationStatusPending && reservation.Status != models.ReservationStatusConfirmed {
+		return nil, fmt.Errorf("%w: a %s reservation cannot be rescheduled", ErrInvalidTransition, reservation.Status)
+	}
+	room, err := s.roomRepo.GetRoomByID(reservation.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSlot(room, date, start, reservation.PlayerID, reservation.ID); err != nil {
+		return nil, err
+	}
+	perks, err := s.playerPerks(reservation.PlayerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPerks(perks, room, date, s.weeklyBookings(perks, reservation.PlayerID, reservation.ID, date)); err != nil {
+		return nil, err
+	}
+
+	details := models.LogDetails{"from_date": reservation.Date.Format("2006-01-02"), "from_time": reservation.Time}
+	rescheduled := *reservation
+	rescheduled.Date = date
+	rescheduled.Time = timeSlot
+	if err := s.reservationRepo.Update(&rescheduled); err != nil {
+		return nil, err
+	}
+	s.logReservation(&rescheduled, models.LogActionReservationRescheduled, details)
+	return &rescheduled, nil
+}
+
+func (s *reservationService) CheckIn(id int) (*models.Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, err := s.reservationRepo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.CanTransitionTo(models.ReservationStatusCheckedIn) {
+		start, end, err := s.slotBounds(reservation)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if now.Before(start.Add(-checkInOpensBefore)) || !now.Before(end) {
+			return nil, ErrCheckInClosed
+		}
+	}
+	return s.transition(id, models.ReservationStatusCheckedIn)
+}
+
+func (s *reservationService) SweepReservations(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := s.reservationRepo.ListByStatus(
+		models.ReservationStatusPending,
+		models.ReservationStatusConfirmed,
+		models.ReservationStatusCheckedIn,
+	)
+
+	swept := 0
+	for _, reservation := range open {
+		_, end, err := s.slotBounds(reservation)
+		if err != nil {
+			log.Printf("Error sweeping reservation %d: %v", reservation.ID, err)
+			continue
+		}
+		if now.Before(end) {
+			continue
+		}
+
+		status := models.ReservationStatusNoShow
+		if reservation.Status == models.ReservationStatusCheckedIn {
+			status = models.ReservationStatusCompleted
+		}
+		if _, err := s.transition(reservation.ID, status); err != nil {
+			return swept, err
+		}
+		swept++
+	}
+	return swept, nil
+}
+
+// statusChange is a reservation that moved from one status to another.
+type statusChange struct {
+	reservation *models.Reservation
+	from        string
+}
+
+// transition moves reservation id to status and logs the change. Callers
+// hold s.mu.
+func (s *reservationService) transition(id int, status string) (*models.Reservation, error) {
+	change, err := changeStatus(s.reservationRepo, id, status)
+	if err != nil {
+		return nil, err
+	}
+	s.logStatusChange(change)
+	return change.reservation, nil
+}
+
+// changeStatus moves reservation id in repo to status, without logging it.
+func changeStatus(repo repositories.ReservationRepository, id int, status string) (statusChange, error) {
+	reservation, err := repo.GetById(id)
+	if err != nil {
+		return statusChange{}, err
+	}
+	if !reservation.CanTransitionTo(status) {
+		return statusChange{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, reservation.Status, status)
+	}
+
+	updated := *reservation
+	updated.Status = status
+	if err := repo.Update(&updated); err != nil {
+		return statusChange{}, err
+	}
+	return statusChange{reservation: &updated, from: reservation.Status}, nil
+}
+
+// logStatusChange publishes a status change, which has already been saved.
+func (s *reservationService) logStatusChange(change statusChange) {
+	s.logReservation(change.reservation, reservationLogActions[change.reservation.Status],
+		models.LogDetails{"from_status": change.from})
+}
+
+// checkSlot returns an error unless the player can book the slot starting
+// start after midnight on date in room. The reservation excludeID, if any, is
+// ignored, so a reservation can be moved to an overlapping slot.
+func (s *reservationService) checkSlot(room *models.Room, date time.Time, start time.Duration, playerID, excludeID int) error {
+	if !room.Bookable() {
+		return fmt.Errorf("%w: room is %s", ErrRoomUnavailable, room.Status)
+	}
+
+	slot := slotLength(room)
+	if !time.Now().Before(utcDate(date).Add(start + slot)) {
+		return ErrSlotEnded
+	}
+	opens, closes, err := openingHours(room)
+	if err != nil {
+		return err
+	}
+	if start < opens || start+slot > closes {
+		return fmt.Errorf("%w: %s to %s", ErrOutsideOpeningHours, formatSlotTime(opens), formatSlotTime(closes))
+	}
+
+	var overlapping []time.Duration
+	for _, existing := range s.reservationRepo.ListByRoomAndDate(room.ID, date) {
+		if existing.ID == excludeID || !existing.Active() {
+			continue
+		}
+		existingStart, err := parseSlotTime(existing.Time)
+		if err != nil || existingStart >= start+slot || start >= existingStart+slot {
+			continue
+		}
+		if existing.PlayerID == playerID {
+			return ErrReservationConflict
+		}
+		overlapping = append(overlapping, existingStart)
+	}
+	if peakOccupancy(overlapping, start, slot) >= max(room.Capacity, 1) {
+		return ErrRoomFull
+	}
+	return nil
+}
+
+// playerPerks returns the perks of the player's level.
+func (s *reservationService) playerPerks(playerID int) (models.LevelPerks, error) {
+	player, err := s.playerRepo.GetPlayerByID(playerID)
+	if err != nil {
+		return models.LevelPerks{}, err
+	}
+	return levelPerks(s.levelRepo, player)
+}
+
+// checkPerks returns an error unless the player's level lets them reserve
+// room on date. booked counts the player's reservations by the week they
+// fall in.
+func checkPerks(perks models.LevelPerks, room *models.Room, date time.Time, booked map[time.Time]int) error {
+	if !perks.MayReserve(room.ID) {
+		return fmt.Errorf("%w: %s", ErrRoomNotAllowed, room.Name)
+	}
+	if perks.MaxWeeklyReservations == 0 {
+		return nil
+	}
+	if booked[weekOf(date)] >= perks.MaxWeeklyReservations {
+		return fmt.Errorf("%w: at most %d a week", ErrWeeklyLimitReached, perks.MaxWeeklyReservations)
+	}
+	return nil
+}
+
+// weeklyBookings counts the player's active reservations in each week from
+// the first to the last of dates, which are in order, leaving out the
+// reservation excludeID. Only levels with a weekly limit need the counts.
+func (s *reservationService) weeklyBookings(perks models.LevelPerks, playerID, excludeID int, dates ...time.Time) map[time.Time]int {
+	booked := make(map[time.Time]int)
+	if perks.MaxWeeklyReservations == 0 || len(dates) == 0 {
+		return booked
+	}
+	from, to := weekOf(dates[0]), weekOf(dates[len(dates)-1]).AddDate(0, 0, 7)
+	for _, reservation := range s.reservationRepo.ListByPlayer(playerID, from, to) {
+		if reservation.ID != excludeID && reservation.Active() {
+			booked[weekOf(reservation.Date)]++
+		}
+	}
+	return booked
+}
+
+// slotBounds returns when the reservation's slot starts and ends, in UTC. A
+// reservation whose room has been deleted keeps the default slot length.
+func (s *reservationService) slotBounds(reservation *models.Reservation) (time.Time, time.Time, error) {
+	offset, err := parseSlotTime(reservation.Time)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	room, err := s.roomRepo.GetRoomByID(reservation.RoomID)
+	if errors.Is(err, repositories.ErrRoomNotFound) {
+		room = &models.Room{}
+	} else if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start := utcDate(reservation.Date).Add(offset)
+	return start, start.Add(slotLength(room)), nil
+}
+
+// utcDate returns midnight UTC on t's calendar date.
+func utcDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// weekOf returns midnight UTC on the Monday starting date's week.
+func weekOf(date time.Time) time.Time {
+	day := utcDate(date)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// openingHours returns how long after midnight the room opens and closes.
+// Rooms stored without opening hours use the defaults.
+func openingHours(room *models.Room) (time.Duration, time.Duration, error) {
+	opensAt, closesAt := room.OpensAt, room.ClosesAt
+	if opensAt == "" {
+		opensAt = models.DefaultRoomOpensAt
+	}
+	if closesAt == "" {
+		closesAt = models.DefaultRoomClosesAt
+	}
+	opens, err := parseSlotTime(opensAt)
+	if err != nil {
+		return 0, 0, err
+	}
+	closes, err := parseSlotTime(closesAt)
+	if err != nil {
+		return 0, 0, err
+	}
+	return opens, closes, nil
+}
+
+// logReservation publishes a reservation change, which has already been
+// saved.
+func (s *reservationService) logReservation(reservation *models.Reservation, action models.LogAction, details models.LogDetails) {
+	if details == nil {
+		details = models.LogDetails{}
+	}
+	details["reservation_id"] = reservation.ID
+	details["room_id"] = reservation.RoomID
+	details["date"] = reservation.Date.Format("2006-01-02")
+	details["time"] = reservation.Time
+	s.events.Publish(Event{
+		Action:   action,
+		PlayerID: reservation.PlayerID,
+		Details:  details,
+	})
+}
+
+// parseSlotTime returns how long after midnight a reservation time starts.
+// "24:00" is accepted as the end of the day, for closing times.
+func parseSlotTime(timeSlot string) (time.Duration, error) {
+	timeSlot = strings.ToUpper(strings.TrimSpace(timeSlot))
+	if timeSlot == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	for _, layout := range slotTimeLayouts {
+		if t, err := time.Parse(layout, timeSlot); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid reservation time %q", timeSlot)
+}
+
+// formatSlotTime formats a time of day given as the time since midnight as
+// HH:MM.
+func formatSlotTime(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// slotLength returns how long a reservation of room lasts.
+func slotLength(room *models.Room) time.Duration {
+	minutes := room.SlotMinutes
+	if minutes <= 0 {
+		minutes = models.DefaultRoomSlotMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// peakOccupancy returns the largest number of the slots starting at starts
+// that are in progress at the same moment during the slot [start, start+slot).
+// The count only rises when a slot begins, so checking those moments is enough.
+func peakOccupancy(starts []time.Duration, start, slot time.Duration) int {
+	peak := 0
+	for _, at := range append([]time.Duration{start}, starts...) {
+		if at < start {
+			at = start
+		}
+		n := 0
+		for _, s := range starts {
+			if s <= at && at < s+slot {
+				n++
+			}
+		}
+		peak = max(peak, n)
+	}
+	return peak
 }
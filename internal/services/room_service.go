@@ -2,28 +2,68 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
 )
 
+var (
+	ErrInvalidRoom            = errors.New("room capacity and slot length must be positive and opening hours HH:MM with opens_at before closes_at")
+	ErrInvalidRoomStatus      = errors.New("room status must be available, maintenance or closed")
+	ErrInvalidRoomTransition  = errors.New("room cannot change to that status")
+	ErrRoomOccupied           = errors.New("room has players inside")
+	ErrRoomAtCapacity         = errors.New("room is at capacity")
+	ErrCapacityBelowOccupancy = errors.New("room capacity is below the number of players inside")
+	ErrRoomNotOpen            = errors.New("room is not open")
+	ErrNotInRoom              = errors.New("player is not in the room")
+)
+
+// RoomOccupancy is who is inside a room right now.
+type RoomOccupancy struct {
+	RoomID    int                   `json:"room_id"`
+	Status    string                `json:"status"`
+	Capacity  int                   `json:"capacity"`
+	Occupants []models.RoomOccupant `json:"occupants"`
+}
+
 type RoomService interface {
 	GetAllRooms() ([]models.Room, error)
 	GetRoomByID(id int) (*models.Room, error)
-	CreateRoom(name, description string) (int, error)
-	UpdateRoom(id int, name, description string) error
+	// CreateRoom adds a room, available unless it is created in maintenance or
+	// closed. A zero capacity, slot length or opening hours fall back to the
+	// defaults.
+	CreateRoom(room models.Room) (int, error)
+	// UpdateRoom changes a room's name, description, capacity, slot length,
+	// opening hours and status. Zero values leave those settings unchanged.
+	UpdateRoom(id int, room models.Room) error
+	// SetRoomStatus puts a room into or takes it out of maintenance, or
+	// closes it. Occupied is set by players entering and cannot be set
+	// directly, and an occupied room must be emptied first.
+	SetRoomStatus(id int, status string) (*models.Room, error)
+	// DeleteRoom deletes an empty room.
 	DeleteRoom(id int) error
+	// EnterRoom puts a player inside a room that is open and below capacity.
+	EnterRoom(roomID, playerID int) (*RoomOccupancy, error)
+	// ExitRoom takes a player out of the room they are in.
+	ExitRoom(roomID, playerID int) (*RoomOccupancy, error)
+	GetOccupancy(roomID int) (*RoomOccupancy, error)
 }
 
 type roomService struct {
-	roomRepo repositories.RoomRepository
-	mu       sync.RWMutex
+	roomRepo   repositories.RoomRepository
+	playerRepo repositories.PlayerRepository
+	events     EventPublisher
+	mu         sync.RWMutex
 }
 
-func NewRoomService(repo repositories.RoomRepository) RoomService {
+func NewRoomService(repo repositories.RoomRepository, playerRepo repositories.PlayerRepository, events EventPublisher) RoomService {
 	return &roomService{
-		roomRepo: repo,
+		roomRepo:   repo,
+		playerRepo: playerRepo,
+		events:     events,
 	}
 }
 
@@ -39,27 +79,53 @@ func (s *roomService) GetRoomByID(id int) (*models.Room, error) {
 	return s.roomRepo.GetRoomByID(id)
 }
 
-func (s *roomService) CreateRoom(name, description string) (int, error) {
+func (s *roomService) CreateRoom(room models.Room) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if room with the same name already exists
 	allRooms, _ := s.roomRepo.GetAllRooms()
-	for _, room := range allRooms {
-		if room.Name == name {
+	for _, existing := range allRooms {
+		if existing.Name == room.Name {
 			return 0, errors.New("room with the same name already exists")
 		}
 	}
 
-	room := models.Room{
-		Name:        name,
-		Description: description,
+	if room.Capacity == 0 {
+		room.Capacity = models.DefaultRoomCapacity
+	}
+	if room.SlotMinutes == 0 {
+		room.SlotMinutes = models.DefaultRoomSlotMinutes
+	}
+	if room.OpensAt == "" {
+		room.OpensAt = models.DefaultRoomOpensAt
+	}
+	if room.ClosesAt == "" {
+		room.ClosesAt = models.DefaultRoomClosesAt
+	}
+	if err := validateRoom(&room); err != nil {
+		return 0, err
+	}
+	switch room.Status {
+	case "":
+		room.Status = models.RoomStatusAvailable
+	case models.RoomStatusAvailable, models.RoomStatusMaintenance, models.RoomStatusClosed:
+	default:
+		return 0, ErrInvalidRoomStatus
 	}
 
-	return s.roomRepo.CreateRoom(room)
+	return s.roomRepo.CreateRoom(models.Room{
+		Name:        room.Name,
+		Description: room.Description,
+		Status:      room.Status,
+		Capacity:    room.Capacity,
+		SlotMinutes: room.SlotMinutes,
+		OpensAt:     room.OpensAt,
+		ClosesAt:    room.ClosesAt,
+	})
 }
 
-func (s *roomService) UpdateRoom(id int, name, description string) error {
+func (s *roomService) UpdateRoom(id int, updated models.Room) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -68,14 +134,249 @@ func (s *roomService) UpdateRoom(id int, name, description string) error {
 		return err
 	}
 
-	room.Name = name
-	room.Description = description
+	room.Name = updated.Name
+	room.Description = updated.Description
+	if updated.Capacity != 0 {
+		room.Capacity = updated.Capacity
+	}
+	if updated.SlotMinutes != 0 {
+		room.SlotMinutes = updated.SlotMinutes
+	}
+	if updated.OpensAt != "" {
+		room.OpensAt = updated.OpensAt
+	}
+	if updated.ClosesAt != "" {
+		room.ClosesAt = updated.ClosesAt
+	}
+	if err := validateRoom(room); err != nil {
+		return err
+	}
+	occupants, err := s.roomRepo.ListOccupants(id)
+	if err != nil {
+		return err
+	}
+	if room.Capacity < len(occupants) {
+		return fmt.Errorf("%w: %d inside", ErrCapacityBelowOccupancy, len(occupants))
+	}
+	from := room.Status
+	if updated.Status != "" && updated.Status != room.Status {
+		if err := s.changeStatus(room, updated.Status); err != nil {
+			return err
+		}
+	}
 
-	return s.roomRepo.UpdateRoom(id, *room)
+	if err := s.roomRepo.UpdateRoom(id, *room); err != nil {
+		return err
+	}
+	if room.Status != from {
+		s.logStatus(0, room, from)
+	}
+	return nil
+}
+
+// validateRoom checks a room's settings and normalizes its opening hours to
+// HH:MM.
+func validateRoom(room *models.Room) error {
+	if room.Capacity <= 0 || room.SlotMinutes <= 0 {
+		return ErrInvalidRoom
+	}
+	opens, err := parseSlotTime(room.OpensAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRoom, err)
+	}
+	closes, err := parseSlotTime(room.ClosesAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRoom, err)
+	}
+	if opens >= closes {
+		return ErrInvalidRoom
+	}
+	room.OpensAt = formatSlotTime(opens)
+	room.ClosesAt = formatSlotTime(closes)
+	return nil
+}
+
+func (s *roomService) SetRoomStatus(id int, status string) (*models.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.roomRepo.GetRoomByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if status == room.Status {
+		return room, nil
+	}
+	from := room.Status
+	if err := s.changeStatus(room, status); err != nil {
+		return nil, err
+	}
+	if err := s.roomRepo.UpdateRoom(id, *room); err != nil {
+		return nil, err
+	}
+	s.logStatus(0, room, from)
+	return room, nil
+}
+
+// changeStatus moves room to a status an operator asked for.
+func (s *roomService) changeStatus(room *models.Room, status string) error {
+	switch status {
+	case models.RoomStatusAvailable, models.RoomStatusMaintenance, models.RoomStatusClosed:
+	default:
+		return ErrInvalidRoomStatus
+	}
+	if room.Status == models.RoomStatusOccupied {
+		return ErrRoomOccupied
+	}
+	if !room.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidRoomTransition, room.Status, status)
+	}
+	room.Status = status
+	return nil
 }
 
 func (s *roomService) DeleteRoom(id int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	occupants, err := s.roomRepo.ListOccupants(id)
+	if err != nil {
+		return err
+	}
+	if len(occupants) > 0 {
+		return ErrRoomOccupied
+	}
 	return s.roomRepo.DeleteRoom(id)
 }
+
+func (s *roomService) EnterRoom(roomID, playerID int) (*RoomOccupancy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.playerRepo.GetPlayerByID(playerID); err != nil {
+		return nil, err
+	}
+	if occupant, err := s.roomRepo.GetOccupant(playerID); err == nil {
+		return nil, fmt.Errorf("%w: room %d", repositories.ErrPlayerAlreadyInRoom, occupant.RoomID)
+	} else if !errors.Is(err, repositories.ErrOccupantNotFound) {
+		return nil, err
+	}
+	if !room.Bookable() {
+		return nil, fmt.Errorf("%w: it is %s", ErrRoomNotOpen, room.Status)
+	}
+	occupants, err := s.roomRepo.ListOccupants(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(occupants) >= room.Capacity {
+		return nil, ErrRoomAtCapacity
+	}
+
+	occupant := models.RoomOccupant{RoomID: roomID, PlayerID: playerID, EnteredAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.roomRepo.AddOccupant(occupant); err != nil {
+		return nil, err
+	}
+	occupants = append(occupants, occupant)
+	if room.Status != models.RoomStatusOccupied {
+		from := room.Status
+		room.Status = models.RoomStatusOccupied
+		if err := s.roomRepo.UpdateRoom(roomID, *room); err != nil {
+			return nil, err
+		}
+		s.logStatus(playerID, room, from)
+	}
+
+	s.logRoom(playerID, models.LogActionEnterRoom, room, len(occupants), nil)
+	return occupancy(room, occupants), nil
+}
+
+func (s *roomService) ExitRoom(roomID, playerID int) (*RoomOccupancy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	occupant, err := s.roomRepo.GetOccupant(playerID)
+	if errors.Is(err, repositories.ErrOccupantNotFound) || (err == nil && occupant.RoomID != roomID) {
+		return nil, ErrNotInRoom
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roomRepo.RemoveOccupant(playerID); err != nil {
+		return nil, err
+	}
+	occupants, err := s.roomRepo.ListOccupants(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(occupants) == 0 && room.Status == models.RoomStatusOccupied {
+		room.Status = models.RoomStatusAvailable
+		if err := s.roomRepo.UpdateRoom(roomID, *room); err != nil {
+			return nil, err
+		}
+		s.logStatus(playerID, room, models.RoomStatusOccupied)
+	}
+
+	stayed := int64(time.Since(occupant.EnteredAt) / time.Second)
+	s.logRoom(playerID, models.LogActionExitRoom, room, len(occupants), models.LogDetails{"stayed_seconds": stayed})
+	return occupancy(room, occupants), nil
+}
+
+func (s *roomService) GetOccupancy(roomID int) (*RoomOccupancy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	occupants, err := s.roomRepo.ListOccupants(roomID)
+	if err != nil {
+		return nil, err
+	}
+	return occupancy(room, occupants), nil
+}
+
+func occupancy(room *models.Room, occupants []models.RoomOccupant) *RoomOccupancy {
+	return &RoomOccupancy{
+		RoomID:    room.ID,
+		Status:    room.Status,
+		Capacity:  room.Capacity,
+		Occupants: occupants,
+	}
+}
+
+// logRoom publishes a player entering or leaving a room, which now holds
+// occupants players.
+func (s *roomService) logRoom(playerID int, action models.LogAction, room *models.Room, occupants int, details models.LogDetails) {
+	if details == nil {
+		details = models.LogDetails{}
+	}
+	details["room_id"] = room.ID
+	details["room_name"] = room.Name
+	details["occupants"] = occupants
+	details["capacity"] = room.Capacity
+	s.events.Publish(Event{
+		Action:   action,
+		PlayerID: playerID,
+		Details:  details,
+	})
+}
+
+// logStatus publishes a room moving from status from to its current status.
+// playerID is the player whose entering or leaving caused it, or 0.
+func (s *roomService) logStatus(playerID int, room *models.Room, from string) {
+	s.events.Publish(Event{
+		Action:   models.LogActionRoomStatusChanged,
+		PlayerID: playerID,
+		Details:  models.LogDetails{"room_id": room.ID, "status": room.Status, "from_status": from},
+	})
+}
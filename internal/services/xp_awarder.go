@@ -0,0 +1,82 @@
+package services
+
+import (
+	"log"
+
+	"oxo_game/internal/models"
+)
+
+// XPRules says how much experience players earn for what they do.
+type XPRules struct {
+	// Challenge is earned for entering a challenge.
+	Challenge int64
+	// JackpotWin is earned on top of Challenge for winning the jackpot.
+	JackpotWin int64
+	// RoomMinute is earned for every full minute spent in a room.
+	RoomMinute int64
+	// CheckIn is earned for checking in to a reservation.
+	CheckIn int64
+}
+
+// DefaultXPRules is used when no experience settings are configured.
+var DefaultXPRules = XPRules{
+	Challenge:  10,
+	JackpotWin: 100,
+	RoomMinute: 1,
+	CheckIn:    5,
+}
+
+// XPAwarder turns the events players cause into experience. It is meant to
+// be a sink of the EventBus.
+type XPAwarder struct {
+	players *PlayerService
+	rules   XPRules
+}
+
+// NewXPAwarder creates an XPAwarder that awards experience by rules.
+func NewXPAwarder(players *PlayerService, rules XPRules) *XPAwarder {
+	return &XPAwarder{
+		players: players,
+		rules:   rules,
+	}
+}
+
+// Publish awards the experience event earns, if any.
+func (a *XPAwarder) Publish(event Event) {
+	xp := a.xpFor(event)
+	if xp <= 0 || event.PlayerID == 0 {
+		return
+	}
+	if _, err := a.players.AwardXP(event.PlayerID, xp, string(event.Action)); err != nil {
+		log.Printf("Error awarding %d XP to player %d for %q: %v", xp, event.PlayerID, event.Action, err)
+	}
+}
+
+func (a *XPAwarder) xpFor(event Event) int64 {
+	switch event.Action {
+	case models.LogActionParticipateChallenge:
+		return a.rules.Challenge
+	case models.LogActionChallengeResult:
+		if won, _ := event.Details["won"].(bool); won {
+			return a.rules.JackpotWin
+		}
+	case models.LogActionExitRoom:
+		return detailInt(event.Details, "stayed_seconds") / 60 * a.rules.RoomMinute
+	case models.LogActionCheckIn:
+		return a.rules.CheckIn
+	}
+	return 0
+}
+
+// detailInt returns an integer detail of an event, or 0 when it is missing.
+func detailInt(details models.LogDetails, name string) int64 {
+	switch v := details[name].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
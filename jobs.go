@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runEvery calls job every interval until ctx is cancelled. Errors are logged
+// and the job is tried again on the next tick.
+func runEvery(ctx context.Context, name string, interval time.Duration, job func(now time.Time) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := job(now); err != nil {
+					log.Printf("Error running %s: %v", name, err)
+				}
+			}
+		}
+	}()
+}
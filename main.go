@@ -1,86 +1,260 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"oxo_game/db"
 	"oxo_game/internal/handlers"
+	"oxo_game/internal/middleware"
+	"oxo_game/internal/models"
 	"oxo_game/internal/repositories"
 	"oxo_game/internal/services"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-	//db := db.GetDB()
-	//fmt.Println(db)
 	// Initialize repositories
-	playerRepo := repositories.NewInMemoryPlayerRepository()
-	levelRepo := repositories.NewInMemoryLevelRepository()
-	roomRepo := repositories.NewInMemoryRoomRepository()
-	// 初始化预约系统的服务、处理器和仓库
-	reservationRepo := repositories.NewInMemoryReservationRepository()
-	logRepo := repositories.NewInMemoryLogRepository()
-	challengeRepo := repositories.NewInMemoryChallengeRepository()
+	var (
+		playerRepo      repositories.PlayerRepository
+		levelRepo       repositories.LevelRepository
+		roomRepo        repositories.RoomRepository
+		reservationRepo repositories.ReservationRepository
+		logRepo         repositories.LogRepository
+		challengeRepo   repositories.ChallengeRepository
+		definitionRepo  repositories.ChallengeDefinitionRepository
+		jackpotRepo     repositories.JackpotRepository
+		paymentRepo     repositories.PaymentRepository
+		ledgerRepo      repositories.LedgerRepository
+		authRepo        repositories.AuthRepository
+		uow             repositories.UnitOfWork
+	)
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		conn, err := db.Open(databaseURL)
+		if err != nil {
+			log.Fatalf("Error connecting to database: %v", err)
+		}
+		defer conn.Close()
+
+		if err := migrateUp(conn); err != nil {
+			log.Fatalf("Error migrating database: %v", err)
+		}
+
+		playerRepo = repositories.NewSQLPlayerRepository(conn)
+		levelRepo = repositories.NewSQLLevelRepository(conn)
+		roomRepo = repositories.NewSQLRoomRepository(conn)
+		reservationRepo = repositories.NewSQLReservationRepository(conn)
+		logRepo = repositories.NewSQLLogRepository(conn)
+		challengeRepo = repositories.NewSQLChallengeRepository(conn)
+		definitionRepo = repositories.NewSQLChallengeDefinitionRepository(conn)
+		jackpotRepo = repositories.NewSQLJackpotRepository(conn)
+		paymentRepo = repositories.NewSQLPaymentRepository(conn)
+		ledgerRepo = repositories.NewSQLLedgerRepository(conn)
+		authRepo = repositories.NewSQLAuthRepository(conn)
+		uow = repositories.NewSQLUnitOfWork(conn)
+	} else {
+		log.Println("DATABASE_URL not set, using in-memory storage")
+
+		playerRepo = repositories.NewInMemoryPlayerRepository()
+		levelRepo = repositories.NewInMemoryLevelRepository()
+		roomRepo = repositories.NewInMemoryRoomRepository()
+		// 初始化预约系统的服务、处理器和仓库
+		reservationRepo = repositories.NewInMemoryReservationRepository()
+		logRepo = repositories.NewInMemoryLogRepository()
+		challengeRepo = repositories.NewInMemoryChallengeRepository()
+		definitionRepo = repositories.NewInMemoryChallengeDefinitionRepository()
+		jackpotRepo = repositories.NewInMemoryJackpotRepository()
+		paymentRepo = repositories.NewInMemoryPaymentRepository()
+		ledgerRepo = repositories.NewInMemoryLedgerRepository()
+		authRepo = repositories.NewInMemoryAuthRepository()
+		uow = repositories.NewInMemoryUnitOfWork(repositories.Repositories{
+			Players:      playerRepo,
+			Levels:       levelRepo,
+			Challenges:   challengeRepo,
+			Jackpot:      jackpotRepo,
+			Payments:     paymentRepo,
+			Ledger:       ledgerRepo,
+			Auth:         authRepo,
+			Reservations: reservationRepo,
+		})
+	}
 
 	// Initialize services
-	playerService := services.NewPlayerService(playerRepo)
-	levelService := services.NewLevelService(levelRepo)
-	roomService := services.NewRoomService(roomRepo)
-	reservationService := services.NewReservationService(reservationRepo)
 	logService := services.NewLogService(logRepo)
-	challengeService := services.NewChallengeService(challengeRepo, playerRepo)
+	// Every event is logged and then streamed to /events subscribers
+	events := services.NewEventBus(envInt("EVENT_HISTORY", 1000), logService)
+	logIngester, err := services.NewLogIngester(logRepo, logIngestConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Error configuring log ingestion: %v", err)
+	}
+	playerService := services.NewPlayerService(playerRepo, levelRepo, uow, events)
+	levelService := services.NewLevelService(levelRepo, roomRepo, uow, playerService)
+	events.AddSink(services.NewXPAwarder(playerService, xpRulesFromEnv()))
+	roomService := services.NewRoomService(roomRepo, playerRepo, events)
+	reservationService := services.NewReservationService(reservationRepo, roomRepo, playerRepo, levelRepo, uow, events)
+	jackpotService, err := services.NewJackpotService(jackpotRepo, jackpotConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Error configuring jackpot: %v", err)
+	}
+	challengeService, err := services.NewChallengeService(challengeRepo, definitionRepo, playerRepo, levelRepo, jackpotService, uow, events)
+	if err != nil {
+		log.Fatalf("Error setting up challenges: %v", err)
+	}
+	paymentService := services.NewPaymentService(paymentRepo, playerRepo, uow, paymentProvidersFromEnv()...)
+	ledgerService := services.NewLedgerService(ledgerRepo, playerRepo, uow)
+	authService := services.NewAuthService(authRepo, playerRepo, uow, events, envDuration("SESSION_TTL", services.DefaultSessionTTL))
+	if username := os.Getenv("ADMIN_USERNAME"); username != "" {
+		if _, err := authService.BootstrapAdmin(username, os.Getenv("ADMIN_PASSWORD")); err != nil {
+			log.Fatalf("Error setting up admin %q: %v", username, err)
+		}
+	}
 
 	// Initialize handlers
 	playersHandler := handlers.NewPlayersHandler(playerService)
 	levelsHandler := handlers.NewLevelsHandler(levelService)
 	roomsHandler := handlers.NewRoomsHandler(roomService)
-	logsHandler := handlers.NewLogsHandler(logService)
+	logsHandler := handlers.NewLogsHandler(logService, logIngester)
+	eventsHandler := handlers.NewEventsHandler(events)
 	challengeHandler := handlers.NewChallengeHandler(challengeService)
+	jackpotHandler := handlers.NewJackpotHandler(jackpotService)
+	paymentsHandler := handlers.NewPaymentsHandler(paymentService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	authHandler := handlers.NewAuthHandler(authService)
 
 	reservationHandler := handlers.NewReservationHandler(reservationService)
 
 	// Setup Gin router
 	router := gin.Default()
+	router.Use(middleware.Authenticate(authService))
+
+	// Routes for accounts
+	router.POST("/auth/register", authHandler.Register)
+	router.POST("/auth/login", authHandler.Login)
+	router.POST("/auth/logout", middleware.RequireAuth(), authHandler.Logout)
+	router.GET("/auth/me", middleware.RequireAuth(), authHandler.Me)
+
+	// Permission checks; anything else is open to everyone
+	auth := middleware.RequireAuth()
+	staff := middleware.RequireRole(models.RoleOperator, models.RoleAdmin)
+	admin := middleware.RequireRole(models.RoleAdmin)
 
 	// Routes for players
 	router.GET("/players", playersHandler.GetAllPlayers)
 	router.GET("/players/:id", playersHandler.GetPlayerByID)
-	router.POST("/players", playersHandler.CreatePlayer)
-	router.PUT("/players/:id", playersHandler.UpdatePlayer)
-	router.DELETE("/players/:id", playersHandler.DeletePlayer)
+	router.POST("/players", admin, playersHandler.CreatePlayer)
+	router.PUT("/players/:id", middleware.RequireSelf("id", models.RoleAdmin), playersHandler.UpdatePlayer)
+	router.DELETE("/players/:id", admin, playersHandler.DeletePlayer)
+	router.PUT("/players/:id/role", admin, playersHandler.SetPlayerRole)
+	router.POST("/players/:id/xp", admin, playersHandler.AwardXP)
+	router.GET("/players/:id/transactions", middleware.RequireSelf("id", models.RoleOperator, models.RoleAdmin), ledgerHandler.ListPlayerTransactions)
+	router.POST("/players/:id/transactions", admin, ledgerHandler.AdjustBalance)
+	router.GET("/ledger/reconciliation", admin, ledgerHandler.Reconcile)
 
 	// Routes for levels
 	router.GET("/levels", levelsHandler.GetAllLevels)
-	router.POST("/levels", levelsHandler.CreateLevel)
+	router.GET("/levels/:id", levelsHandler.GetLevelByID)
+	router.POST("/levels", admin, levelsHandler.CreateLevel)
+	router.PUT("/levels/:id", admin, levelsHandler.UpdateLevel)
+	router.DELETE("/levels/:id", admin, levelsHandler.DeleteLevel)
 
 	router.GET("/rooms", roomsHandler.GetAllRooms)
 	router.GET("/rooms/:id", roomsHandler.GetRoomByID)
-	router.POST("/rooms", roomsHandler.CreateRoom)
-	router.PUT("/rooms/:id", roomsHandler.UpdateRoom)
-	router.DELETE("/rooms/:id", roomsHandler.DeleteRoom)
+	router.POST("/rooms", staff, roomsHandler.CreateRoom)
+	router.PUT("/rooms/:id", staff, roomsHandler.UpdateRoom)
+	router.DELETE("/rooms/:id", admin, roomsHandler.DeleteRoom)
+	router.PUT("/rooms/:id/status", staff, roomsHandler.SetRoomStatus)
+	router.GET("/rooms/:id/occupants", roomsHandler.GetOccupancy)
+	router.POST("/rooms/:id/enter", auth, roomsHandler.EnterRoom)
+	router.POST("/rooms/:id/exit", auth, roomsHandler.ExitRoom)
+	router.GET("/rooms/:id/availability", reservationHandler.GetRoomAvailability)
 
 	router.GET("/reservations", reservationHandler.ListReservations)
-	router.POST("/reservations", reservationHandler.CreateReservation)
+	router.POST("/reservations", auth, reservationHandler.CreateReservation)
+	router.POST("/reservations/series", auth, reservationHandler.CreateReservationSeries)
+	router.GET("/reservations/series/:id", reservationHandler.GetReservationSeries)
+	router.POST("/reservations/series/:id/cancel", auth, reservationHandler.CancelReservationSeries)
+	router.GET("/reservations/:id", reservationHandler.GetReservationByID)
+	router.POST("/reservations/:id/confirm", auth, reservationHandler.ConfirmReservation)
+	router.POST("/reservations/:id/cancel", auth, reservationHandler.CancelReservation)
+	router.POST("/reservations/:id/reschedule", auth, reservationHandler.RescheduleReservation)
+	router.POST("/reservations/:id/check-in", auth, reservationHandler.CheckIn)
 
-	router.POST("/challenges", challengeHandler.ParticipateChallenge)
+	router.POST("/challenges", auth, challengeHandler.ParticipateChallenge)
 	router.GET("/challenges/results", challengeHandler.ListLatestChallenges)
+	router.GET("/challenges/definitions", challengeHandler.ListDefinitions)
+	router.GET("/challenges/definitions/:id", challengeHandler.GetDefinition)
+	router.POST("/challenges/definitions", admin, challengeHandler.CreateDefinition)
+	router.PUT("/challenges/definitions/:id", admin, challengeHandler.UpdateDefinition)
+	router.DELETE("/challenges/definitions/:id", admin, challengeHandler.DeleteDefinition)
+	router.POST("/challenges/definitions/:id/join", auth, challengeHandler.JoinChallenge)
+
+	router.GET("/jackpot", jackpotHandler.GetPool)
+	router.GET("/jackpot/payouts", jackpotHandler.ListPayouts)
+
+	router.GET("/payments", auth, paymentsHandler.ListPayments)
+	router.GET("/payments/methods", paymentsHandler.ListMethods)
+	router.GET("/payments/:id", auth, paymentsHandler.GetPaymentByID)
+	router.POST("/payments", auth, paymentsHandler.TopUp)
 
 	// Logs endpoints
-	router.GET("/logs", logsHandler.GetAllLogs)
-	router.POST("/logs", logsHandler.CreateLog)
+	router.GET("/logs", logsHandler.QueryLogs)
+	router.GET("/logs/schemas", logsHandler.GetLogSchemas)
+	router.GET("/logs/export", staff, logsHandler.ExportLogs)
+	router.GET("/logs/:id", logsHandler.GetLogByID)
+	router.POST("/logs", staff, logsHandler.CreateLog)
+	router.POST("/logs/batch", staff, logsHandler.CreateLogBatch)
+	router.GET("/logs/batch/stats", staff, logsHandler.GetIngestStats)
+	router.DELETE("/logs/:id", admin, logsHandler.DeleteLog)
 
-	// Start server
-	router.Run(":8080")
+	router.GET("/events", eventsHandler.StreamEvents)
+
+	// Background jobs run until the server shuts down
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	runEvery(ctx, "reservation sweeper", envDuration("RESERVATION_SWEEP_INTERVAL", time.Minute), func(now time.Time) error {
+		swept, err := reservationService.SweepReservations(now)
+		if swept > 0 {
+			log.Printf("Closed %d ended reservations", swept)
+		}
+		return err
+	})
+	if retention := logRetentionFromEnv(); retention.Default > 0 || len(retention.Actions) > 0 {
+		dir := os.Getenv("LOG_ARCHIVE_DIR")
+		if dir == "" {
+			dir = "log-archive"
+		}
+		archiver := services.NewLogArchiver(logRepo, dir, retention)
+		runEvery(ctx, "log archiver", envDuration("LOG_ARCHIVE_INTERVAL", time.Hour), func(now time.Time) error {
+			archived, err := archiver.Archive(now)
+			if archived > 0 {
+				log.Printf("Archived %d expired logs to %s", archived, dir)
+			}
+			return err
+		})
+	}
+	runEvery(ctx, "session sweeper", envDuration("SESSION_SWEEP_INTERVAL", time.Hour), func(now time.Time) error {
+		_, err := authService.SweepSessions(now)
+		return err
+	})
 
 	// Start HTTP server
 	server := &http.Server{
 		Addr:    ":8080",
 		Handler: router,
 	}
+	// Event streams never end by themselves
+	server.RegisterOnShutdown(events.Close)
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -89,13 +263,15 @@ func main() {
 	}()
 
 	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 	log.Println("Shutting down server...")
 
-	if err := server.Shutdown(nil); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Error shutting down server: %v", err)
 	}
+	// Write the logs still queued before exiting
+	logIngester.Close()
 	log.Println("Server stopped.")
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"oxo_game/db"
+)
+
+const migrateUsage = "usage: oxo_game migrate up|down [steps]|status"
+
+// runMigrate implements the "migrate" subcommand against DATABASE_URL.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL must be set to run migrations")
+	}
+	conn, err := db.Open(databaseURL)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error migrating up: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error migrating down: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+// migrateUp applies pending migrations when the server starts.
+func migrateUp(conn *sql.DB) error {
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	return err
+}